@@ -2,15 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/fxfuren/yandex-watcher-bot/internal/bot"
 	"github.com/fxfuren/yandex-watcher-bot/internal/client"
 	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/health"
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
 	"github.com/fxfuren/yandex-watcher-bot/internal/monitoring"
 	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/server"
+	"github.com/fxfuren/yandex-watcher-bot/internal/state"
 	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
 	"github.com/joho/godotenv"
 )
@@ -21,12 +29,45 @@ func main() {
 
 	logger.Info("🚀 Yandex VM Watchdog Bot starting...")
 
+	// Setup graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigChan
+		logger.Info("⚠️ Received signal, shutting down gracefully...",
+			"signal", sig.String(),
+		)
+		cancel()
+	}()
+
+	// Start HTTP endpoint before loading the configuration so a broken
+	// configuration is visible on /healthz
+	checker := health.NewChecker()
+	configHealth := &health.State{}
+	checker.AddLiveness("config", configHealth.Check)
+
+	var httpServer *server.Server
+	httpEnabled, httpAddr := config.HTTPSettings()
+	if httpEnabled {
+		httpServer = startHTTPServer(ctx, httpAddr, checker)
+	}
+
 	// Load configuration
 	cfg, err := config.Load("vms.yaml")
 	if err != nil {
 		logger.Critical("Failed to load configuration",
 			"error", err,
 		)
+		configHealth.Set(err)
+		if httpEnabled {
+			// Keep reporting the failure until the container is stopped
+			<-ctx.Done()
+		}
 		os.Exit(1)
 	}
 
@@ -38,33 +79,96 @@ func main() {
 
 	// Create clients
 	yandexClient := client.NewYandexClient()
+
+	var computeClient *client.ComputeClient
+	if cfg.ServiceAccountKey != "" {
+		computeClient, err = newComputeClient(cfg)
+		if err != nil {
+			logger.Critical("Failed to configure Compute API client",
+				"error", err,
+			)
+			os.Exit(1)
+		}
+	}
+
 	telegramClient := notification.NewTelegramClient(cfg.BotToken, cfg.GroupChatID, cfg.TopicID)
 
+	// Create notification channels
+	var liveMessages *notification.LiveMessages
+	if cfg.TelegramLive {
+		liveMessages, err = notification.NewLiveMessages(telegramClient, filepath.Join(cfg.StateDir, "live_messages.json"), cfg.NotificationMaxAge)
+		if err != nil {
+			logger.Critical("Failed to load live messages",
+				"error", err,
+			)
+			os.Exit(1)
+		}
+	}
+
+	channels, err := buildChannels(cfg, telegramClient, liveMessages)
+	if err != nil {
+		logger.Critical("Failed to configure notifiers",
+			"error", err,
+		)
+		os.Exit(1)
+	}
+
 	// Create notification queue
-	notifier := notification.NewNotificationQueue(telegramClient, cfg.TelegramWorkers)
+	notifier := notification.NewNotificationQueue(cfg.TelegramWorkers, channels...)
+	outbox, err := notification.OpenOutbox(filepath.Join(cfg.StateDir, "outbox.jsonl"), cfg.NotificationMaxAge)
+	if err != nil {
+		// Notifications are still sent and retried, just not across restarts
+		logger.Error("Failed to open notification outbox",
+			"error", err,
+		)
+	} else {
+		notifier.UseOutbox(outbox)
+	}
 	notifier.Start()
 
 	// Create coordinator
-	coordinator := monitoring.NewCoordinator(cfg, yandexClient, notifier)
+	coordinator := monitoring.NewCoordinator(cfg, yandexClient, computeClient, notifier)
 
-	// Setup graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	stateStore, err := state.Open(filepath.Join(cfg.StateDir, "state.json"))
+	if err != nil {
+		// Monitoring still works without persisted state, it just starts from scratch
+		logger.Error("Failed to load monitor state",
+			"error", err,
+		)
+	} else {
+		coordinator.SetStateStore(stateStore)
+	}
 
-	// Handle signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	coordinator.SetConfigHealth(configHealth)
+	checker.AddLiveness("monitors", coordinator.CheckMonitors)
+	checker.AddLiveness("notifications", notifier.CheckWorkers)
+	checker.AddReadiness("first_check", coordinator.CheckReady)
+
+	// Start monitoring
+	coordinator.Start(ctx)
 
+	// Pause/resume API, only with a token configured
+	if httpServer != nil && cfg.HTTPAPIToken != "" {
+		httpServer.Handle("/api/", server.NewAPI(coordinator, cfg.HTTPAPIToken))
+	}
+
+	// Reload vms.yaml on SIGHUP
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
 	go func() {
-		sig := <-sigChan
-		logger.Info("⚠️ Received signal, shutting down gracefully...",
-			"signal", sig.String(),
-		)
-		cancel()
+		for range hupChan {
+			coordinator.RequestReload()
+		}
 	}()
 
-	// Start monitoring
-	coordinator.Start(ctx)
+	// Start Telegram command handling
+	if cfg.TelegramCommands {
+		commandBot := bot.NewBot(telegramClient, coordinator)
+		if liveMessages != nil {
+			commandBot.SetLiveMessages(liveMessages)
+		}
+		go commandBot.Run(ctx)
+	}
 
 	// Wait for context cancellation
 	<-ctx.Done()
@@ -90,3 +194,84 @@ func main() {
 
 	logger.Info("👋 Yandex VM Watchdog Bot stopped")
 }
+
+// startHTTPServer serves metrics and health endpoints until ctx is cancelled
+func startHTTPServer(ctx context.Context, addr string, checker *health.Checker) *server.Server {
+	httpServer := server.New(addr)
+	httpServer.Handle("/metrics", metrics.Handler())
+	httpServer.Handle("/healthz", checker.LivenessHandler())
+	httpServer.Handle("/readyz", checker.ReadinessHandler())
+
+	go func() {
+		if err := httpServer.Run(ctx); err != nil {
+			logger.Error("HTTP server failed",
+				"addr", addr,
+				"error", err,
+			)
+		}
+	}()
+	return httpServer
+}
+
+// buildChannels creates the enabled notification backends
+func buildChannels(cfg *config.Config, telegramClient *notification.TelegramClient, live *notification.LiveMessages) ([]notification.Channel, error) {
+	var channels []notification.Channel
+
+	add := func(base config.NotifierBase, notifier notification.Notifier) error {
+		if !base.Enabled {
+			return nil
+		}
+		minPriority, err := notification.ParsePriority(base.MinPriority)
+		if err != nil {
+			return fmt.Errorf("%s: %w", notifier.Name(), err)
+		}
+		channels = append(channels, notification.Channel{
+			Notifier:       notifier,
+			MinPriority:    minPriority,
+			EscalationOnly: base.EscalationOnly,
+		})
+		logger.Info("Notification channel enabled",
+			"channel", notifier.Name(),
+			"min_priority", minPriority,
+			"escalation_only", base.EscalationOnly,
+		)
+		return nil
+	}
+
+	n := cfg.Notifiers
+	email := n.Email
+	err := errors.Join(
+		add(n.Telegram.NotifierBase, notification.NewTelegramNotifier(telegramClient, live)),
+		add(n.Webhook.NotifierBase, notification.NewWebhookNotifier(n.Webhook.URL, n.Webhook.Headers)),
+		add(n.Slack.NotifierBase, notification.NewSlackNotifier(n.Slack.WebhookURL)),
+		add(email.NotifierBase, notification.NewEmailNotifier(email.Host, email.Port, email.Username, email.Password, email.From, email.To)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(channels) == 0 {
+		logger.Warn("No notification channels enabled")
+	}
+
+	return channels, nil
+}
+
+// newComputeClient creates a Compute API client authenticated with the service account key
+func newComputeClient(cfg *config.Config) (*client.ComputeClient, error) {
+	key, err := client.LoadServiceAccountKey(cfg.ServiceAccountKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := client.NewIAMTokenSource(key, cfg.IAMEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Compute API backend enabled",
+		"service_account", key.ServiceAccountID,
+	)
+
+	return client.NewComputeClient(tokens, cfg.ComputeEndpoint, cfg.OperationEndpoint), nil
+}
@@ -0,0 +1,540 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/monitoring"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+const (
+	// DefaultPollTimeout is how long a single getUpdates call waits for new updates
+	DefaultPollTimeout = 30 * time.Second
+	// retryDelay is the pause after a failed getUpdates call
+	retryDelay = 5 * time.Second
+	// muteDuration is how long the "mute" alert button silences a VM
+	muteDuration = time.Hour
+	// defaultPauseDuration is how long /pause holds a VM when no duration is given
+	defaultPauseDuration = time.Hour
+	// startTimeout bounds a start requested from the chat, API retries included
+	startTimeout = 2 * time.Minute
+)
+
+// Controller is the part of the coordinator the bot exposes to the chat
+type Controller interface {
+	VMStates() []monitoring.VMState
+	VMState(name string) (monitoring.VMState, bool)
+	StartVM(ctx context.Context, name string) error
+	CheckVM(name string) error
+	MuteVM(name string, d time.Duration) error
+	AcknowledgeVM(name, by string) error
+	PauseVM(name string, d time.Duration, by, reason string) error
+	ResumeVM(name string) error
+}
+
+// Bot reads commands from the group chat via long polling and dispatches them
+type Bot struct {
+	client      *notification.TelegramClient
+	controller  Controller
+	live        *notification.LiveMessages
+	pollTimeout time.Duration
+	offset      int
+	starts      sync.WaitGroup // starts requested from the chat
+}
+
+// NewBot creates a new command bot
+func NewBot(client *notification.TelegramClient, controller Controller) *Bot {
+	return &Bot{
+		client:      client,
+		controller:  controller,
+		pollTimeout: DefaultPollTimeout,
+	}
+}
+
+// SetLiveMessages makes button actions on live messages go through the live
+// message store, so later incident updates keep the annotation
+func (b *Bot) SetLiveMessages(live *notification.LiveMessages) {
+	b.live = live
+}
+
+// Run polls for updates until ctx is cancelled
+func (b *Bot) Run(ctx context.Context) {
+	logger.Info("🤖 Telegram command bot started")
+	defer b.starts.Wait()
+
+	for {
+		if ctx.Err() != nil {
+			logger.Info("Telegram command bot stopping")
+			return
+		}
+
+		updates, err := b.client.GetUpdates(ctx, b.offset, b.pollTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.Info("Telegram command bot stopping")
+				return
+			}
+
+			logger.Warn("⚠️ Failed to get Telegram updates",
+				"error", err,
+			)
+
+			select {
+			case <-time.After(max(retryDelay, notification.RetryAfter(err))):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			// Acknowledge the update even if handling fails so it is not redelivered forever
+			b.offset = update.UpdateID + 1
+			b.handleUpdate(ctx, update)
+		}
+	}
+}
+
+func (b *Bot) handleUpdate(ctx context.Context, update notification.Update) {
+	if update.CallbackQuery != nil {
+		b.handleCallback(ctx, update.CallbackQuery)
+		return
+	}
+
+	msg := update.Message
+	if msg == nil || !strings.HasPrefix(msg.Text, "/") {
+		return
+	}
+
+	// Only accept commands from the configured group
+	if msg.Chat.ID != b.client.GroupChatID() {
+		logger.Warn("Ignoring command from foreign chat",
+			"chat_id", msg.Chat.ID,
+			"user", msg.From.DisplayName(),
+		)
+		return
+	}
+
+	command, args := parseCommand(msg.Text)
+
+	logger.Info("💬 Telegram command received",
+		"command", command,
+		"args", strings.Join(args, " "),
+		"user", msg.From.DisplayName(),
+	)
+
+	reply := b.handleCommand(ctx, msg.From.DisplayName(), command, args)
+	if reply == "" {
+		return
+	}
+
+	if err := b.client.SendMessage(ctx, reply); err != nil {
+		logger.Error("❌ Failed to send command reply",
+			"command", command,
+			"error", err,
+		)
+	}
+}
+
+// handleCommand executes a command sent by the named user and returns the reply
+func (b *Bot) handleCommand(ctx context.Context, from, command string, args []string) string {
+	switch command {
+	case "/status":
+		if len(args) == 0 {
+			return b.statusAll()
+		}
+		return b.statusOne(args[0])
+
+	case "/start":
+		if len(args) == 0 {
+			return "Использование: /start <vm>"
+		}
+		vmName := args[0]
+		if _, ok := b.controller.VMState(vmName); !ok {
+			return formatError(vmName, monitoring.ErrUnknownVM)
+		}
+		// A start retries the API with backoff: reply now and report the
+		// outcome once the start is done, so other updates are not held up
+		b.startVM(ctx, vmName, from, func(err error) {
+			reply := fmt.Sprintf("✅ Запуск ВМ *%s* выполнен.", vmName)
+			if err != nil {
+				reply = formatError(vmName, err)
+			}
+			if sendErr := b.client.SendMessage(ctx, reply); sendErr != nil {
+				logger.Error("❌ Failed to send start result",
+					"vm", vmName,
+					"error", sendErr,
+				)
+			}
+		})
+		return fmt.Sprintf("🚀 Запрос на запуск ВМ *%s* отправлен.", vmName)
+
+	case "/check":
+		if len(args) == 0 {
+			return "Использование: /check <vm>"
+		}
+		if err := b.controller.CheckVM(args[0]); err != nil {
+			return formatError(args[0], err)
+		}
+		return fmt.Sprintf("🔍 Проверка ВМ *%s* запланирована.", args[0])
+
+	case "/pause":
+		if len(args) == 0 {
+			return "Использование: /pause <vm> [длительность] [причина]"
+		}
+		d, reason := parsePauseArgs(args[1:])
+		if d <= 0 {
+			return "❌ Длительность паузы должна быть положительной, например 30m или 2h."
+		}
+		if err := b.controller.PauseVM(args[0], d, from, reason); err != nil {
+			return formatError(args[0], err)
+		}
+		return fmt.Sprintf("⏸️ ВМ *%s* на паузе до %s: автозапуск и оповещения о сбоях приостановлены.",
+			args[0], time.Now().Add(d).Format("02.01 15:04"))
+
+	case "/resume":
+		if len(args) == 0 {
+			return "Использование: /resume <vm>"
+		}
+		if err := b.controller.ResumeVM(args[0]); err != nil {
+			return formatError(args[0], err)
+		}
+		return fmt.Sprintf("▶️ Пауза ВМ *%s* снята.", args[0])
+
+	case "/help":
+		return helpText
+
+	default:
+		// Unknown commands may be meant for other bots in the group
+		return ""
+	}
+}
+
+// handleCallback executes an alert button action and marks the alert with who acted and when
+func (b *Bot) handleCallback(ctx context.Context, cb *notification.CallbackQuery) {
+	msg := cb.Message
+	if msg == nil || msg.Chat.ID != b.client.GroupChatID() {
+		b.answer(ctx, cb.ID, "", false)
+		return
+	}
+
+	action, vmName, ok := notification.ParseCallbackData(cb.Data)
+	if !ok {
+		b.answer(ctx, cb.ID, "", false)
+		return
+	}
+
+	user := cb.From.DisplayName()
+	logger.Info("🔘 Alert action received",
+		"action", action,
+		"vm", vmName,
+		"user", user,
+	)
+
+	var (
+		note string
+		err  error
+	)
+
+	switch action {
+	case notification.ActionStart:
+		// A start retries the API with backoff: answer the button now and
+		// note the outcome on the alert once the start is done
+		b.answer(ctx, cb.ID, "🚀 Запуск инициирован", false)
+		b.startVM(ctx, vmName, user, func(err error) {
+			note := "🚀 Запуск инициирован"
+			if err != nil {
+				note = "❌ Запуск не удался: " + err.Error()
+			}
+			b.annotate(ctx, msg, vmName, fmt.Sprintf("%s — %s, %s", note, user, time.Now().Format("15:04:05")))
+		})
+		return
+
+	case notification.ActionMute:
+		err = b.controller.MuteVM(vmName, muteDuration)
+		note = "🔕 Уведомления отключены на 1ч"
+
+	case notification.ActionAck:
+		err = b.controller.AcknowledgeVM(vmName, user)
+		note = "👀 Принято в работу"
+
+	case notification.ActionDetails:
+		state, found := b.controller.VMState(vmName)
+		if !found {
+			err = monitoring.ErrUnknownVM
+			break
+		}
+		b.answer(ctx, cb.ID, "", false)
+		if sendErr := b.client.SendMessage(ctx, FormatVMState(state)); sendErr != nil {
+			logger.Error("❌ Failed to send VM details",
+				"vm", vmName,
+				"error", sendErr,
+			)
+		}
+		return
+
+	default:
+		b.answer(ctx, cb.ID, "", false)
+		return
+	}
+
+	if errors.Is(err, monitoring.ErrNoIncident) {
+		b.answer(ctx, cb.ID, "ℹ️ Инцидент уже закрыт", true)
+		return
+	}
+	if err != nil {
+		b.answer(ctx, cb.ID, "❌ "+err.Error(), true)
+		return
+	}
+
+	b.answer(ctx, cb.ID, note, false)
+	b.annotate(ctx, msg, vmName, fmt.Sprintf("%s — %s, %s", note, user, time.Now().Format("15:04:05")))
+}
+
+// startVM starts a VM in the background, bounded by startTimeout, and passes
+// the outcome to done once the start is over
+func (b *Bot) startVM(ctx context.Context, vmName, user string, done func(error)) {
+	b.starts.Add(1)
+	go func() {
+		defer b.starts.Done()
+
+		startCtx, cancel := context.WithTimeout(ctx, startTimeout)
+		defer cancel()
+
+		err := b.controller.StartVM(startCtx, vmName)
+		if err != nil {
+			logger.Error("❌ Failed to start VM from the chat",
+				"vm", vmName,
+				"user", user,
+				"error", err,
+			)
+		}
+		done(err)
+	}()
+}
+
+// annotate appends a line to an alert message
+func (b *Bot) annotate(ctx context.Context, msg *notification.Message, vmName, line string) {
+	if b.live != nil {
+		handled, err := b.live.Annotate(ctx, msg.MessageID, notification.EscapeMarkdown(line))
+		if handled {
+			if err != nil {
+				logger.Error("❌ Failed to annotate live message",
+					"vm", vmName,
+					"error", err,
+				)
+			}
+			return
+		}
+	}
+
+	edit := notification.MessageEdit{
+		MessageID: msg.MessageID,
+		Text:      msg.Text + "\n\n" + line,
+		Entities:  msg.Entities,
+		Keyboard:  msg.ReplyMarkup,
+	}
+	if edit.Entities == nil {
+		// Plain text without formatting must not be re-parsed as Markdown
+		edit.Entities = json.RawMessage("[]")
+	}
+
+	if err := b.client.EditMessageText(ctx, edit); err != nil {
+		logger.Error("❌ Failed to annotate alert message",
+			"vm", vmName,
+			"error", err,
+		)
+	}
+}
+
+func (b *Bot) answer(ctx context.Context, callbackID, text string, showAlert bool) {
+	if err := b.client.AnswerCallbackQuery(ctx, callbackID, text, showAlert); err != nil {
+		logger.Warn("⚠️ Failed to answer callback query",
+			"error", err,
+		)
+	}
+}
+
+func (b *Bot) statusAll() string {
+	states := b.controller.VMStates()
+	if len(states) == 0 {
+		return "Нет ВМ под мониторингом."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📊 *Статус ВМ*\n")
+	for _, s := range states {
+		fmt.Fprintf(&sb, "\n%s *%s* — %s (%s)", statusEmoji(s.Status), s.Name, s.Status, since(s.Since))
+		if s.InMaintenance {
+			sb.WriteString(" 🔧")
+		}
+		if s.AppDown {
+			sb.WriteString(" 🩺")
+		}
+		if s.Flapping {
+			sb.WriteString(" 🔀")
+		}
+	}
+	return sb.String()
+}
+
+func (b *Bot) statusOne(name string) string {
+	s, ok := b.controller.VMState(name)
+	if !ok {
+		return formatError(name, monitoring.ErrUnknownVM)
+	}
+	return FormatVMState(s)
+}
+
+// FormatVMState renders a detailed status message for a VM
+func FormatVMState(s monitoring.VMState) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s *%s*\n\n", statusEmoji(s.Status), s.Name)
+	fmt.Fprintf(&sb, "Статус: %s (%s)\n", s.Status, since(s.Since))
+
+	ip := s.IP
+	if ip == "" {
+		ip = "неизвестен"
+	}
+	fmt.Fprintf(&sb, "IP: %s\n", ip)
+
+	if !s.LastAPICheck.IsZero() {
+		fmt.Fprintf(&sb, "Последний запрос к API: %s назад\n", since(s.LastAPICheck))
+	}
+	if s.InMaintenance {
+		if time.Now().Before(s.PausedUntil) {
+			line := fmt.Sprintf("⏸️ Пауза до %s (%s)", s.PausedUntil.Format("02.01 15:04"), s.PausedBy)
+			if s.PauseReason != "" {
+				line += ": " + s.PauseReason
+			}
+			sb.WriteString(line + "\n")
+		} else {
+			sb.WriteString("🔧 Окно обслуживания: автозапуск и оповещения приостановлены\n")
+		}
+	}
+	switch {
+	case s.Desired == types.DesiredUnmanaged:
+		sb.WriteString("👁️ Только наблюдение: бот не запускает и не останавливает ВМ\n")
+	case s.Desired == types.DesiredStopped && s.Scheduled:
+		sb.WriteString("🕗 Вне расписания: ВМ должна быть выключена\n")
+	case s.Desired == types.DesiredStopped:
+		sb.WriteString("💤 ВМ должна быть выключена (desired_state: stopped)\n")
+	}
+	if s.AppDown {
+		fmt.Fprintf(&sb, "🩺 Проверки не проходят: %s\n", notification.EscapeMarkdown(s.AppFailure))
+		switch {
+		case s.RemedyExhausted && !s.RestartedAt.IsZero():
+			fmt.Fprintf(&sb, "❌ Перезапуск %s назад не помог, нужно вмешательство\n", since(s.RestartedAt))
+		case s.RemedyExhausted:
+			sb.WriteString("❌ Перезапустить ВМ не удалось, нужно вмешательство\n")
+		case !s.RestartedAt.IsZero():
+			fmt.Fprintf(&sb, "🔄 Перезапущена %s назад, жду восстановления\n", since(s.RestartedAt))
+		}
+	}
+	if s.Flapping {
+		fmt.Fprintf(&sb, "🔀 Флаппинг (%.0f%% изменений): оповещения о сбоях приостановлены\n", s.FlapPercent)
+	}
+	if s.CrashLooping {
+		sb.WriteString("🔁 Crash loop: автозапуск приостановлен до /start\n")
+	}
+	if s.Incident != nil {
+		writeIncident(&sb, s.Incident)
+	} else if s.LastIncident != nil {
+		fmt.Fprintf(&sb, "Последний инцидент `%s`: простой %s, закрыт %s назад\n",
+			s.LastIncident.ID, s.LastIncident.Downtime().Round(time.Second), since(s.LastIncident.ResolvedAt))
+	}
+	if time.Now().Before(s.GracePeriodUntil) {
+		fmt.Fprintf(&sb, "Grace period: ещё %s\n", time.Until(s.GracePeriodUntil).Round(time.Second))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// incidentEventLines is how much of an incident's timeline the details show
+const incidentEventLines = 5
+
+// writeIncident renders the open incident with its latest events
+func writeIncident(sb *strings.Builder, inc *monitoring.Incident) {
+	fmt.Fprintf(sb, "Инцидент `%s` открыт: %s назад\n", inc.ID, since(inc.OpenedAt))
+	if inc.AcknowledgedBy != "" {
+		fmt.Fprintf(sb, "Принято: %s, %s назад\n", notification.EscapeMarkdown(inc.AcknowledgedBy), since(inc.AcknowledgedAt))
+	}
+	if inc.Escalations > 0 {
+		fmt.Fprintf(sb, "⏫ Эскалаций: %d\n", inc.Escalations)
+	}
+
+	events := inc.Timeline
+	if len(events) > incidentEventLines {
+		events = events[len(events)-incidentEventLines:]
+	}
+	for _, e := range events {
+		fmt.Fprintf(sb, "• %s %s\n", e.At.Format("15:04:05"), notification.EscapeMarkdown(e.Text))
+	}
+}
+
+const helpText = `🤖 *Команды*
+
+/status — статус всех ВМ
+/status <vm> — подробный статус ВМ
+/start <vm> — запустить ВМ
+/check <vm> — проверить ВМ немедленно
+/pause <vm> [длительность] [причина] — приостановить автозапуск и оповещения (по умолчанию 1h)
+/resume <vm> — снять паузу
+/help — эта справка`
+
+// parseCommand splits "/cmd@BotName arg1 arg2" into "/cmd" and its arguments
+func parseCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	command := strings.ToLower(fields[0])
+	if i := strings.Index(command, "@"); i >= 0 {
+		command = command[:i]
+	}
+	return command, fields[1:]
+}
+
+// parsePauseArgs reads an optional leading duration and a free-form reason
+func parsePauseArgs(args []string) (time.Duration, string) {
+	if len(args) > 0 {
+		if d, err := time.ParseDuration(args[0]); err == nil {
+			return d, strings.Join(args[1:], " ")
+		}
+	}
+	return defaultPauseDuration, strings.Join(args, " ")
+}
+
+func formatError(vmName string, err error) string {
+	switch {
+	case errors.Is(err, monitoring.ErrUnknownVM):
+		return fmt.Sprintf("❓ ВМ *%s* не найдена. /status — список ВМ.", vmName)
+	case errors.Is(err, monitoring.ErrNotPaused):
+		return fmt.Sprintf("ℹ️ ВМ *%s* не на паузе.", vmName)
+	}
+	return fmt.Sprintf("❌ ВМ *%s*: %s", vmName, notification.EscapeMarkdown(err.Error()))
+}
+
+func statusEmoji(status types.VMStatus) string {
+	switch {
+	case status.IsCritical():
+		return "🚨"
+	case status == types.StatusRunning:
+		return "✅"
+	case status.IsTransitional():
+		return "⏳"
+	default:
+		return "❔"
+	}
+}
+
+func since(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
@@ -0,0 +1,438 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/monitoring"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+const testChatID = int64(-100123)
+
+// fakeTelegram serves getUpdates from a fixed list and records sent messages
+type fakeTelegram struct {
+	mu      sync.Mutex
+	updates []notification.Update
+	offsets []int
+	sent    []string
+	edits   []map[string]interface{}
+	answers []string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var result interface{} = true
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+		offset := int(payload["offset"].(float64))
+		f.offsets = append(f.offsets, offset)
+
+		pending := []notification.Update{}
+		for _, u := range f.updates {
+			if u.UpdateID >= offset {
+				pending = append(pending, u)
+			}
+		}
+		result = pending
+	case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+		f.sent = append(f.sent, payload["text"].(string))
+	case strings.HasSuffix(r.URL.Path, "/editMessageText"):
+		f.edits = append(f.edits, payload)
+	case strings.HasSuffix(r.URL.Path, "/answerCallbackQuery"):
+		text, _ := payload["text"].(string)
+		f.answers = append(f.answers, text)
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
+
+func (f *fakeTelegram) sentMessages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+type fakeController struct {
+	mu      sync.Mutex
+	started []string
+	// startGate, when set, holds StartVM until it is closed
+	startGate chan struct{}
+	checked   []string
+	muted     []string
+	acked     []string
+	paused    []string
+	resumed   []string
+}
+
+func (c *fakeController) VMStates() []monitoring.VMState {
+	return []monitoring.VMState{
+		{Name: "web", Status: types.StatusRunning, Since: time.Now()},
+		{Name: "db", Status: types.StatusStopped, Since: time.Now()},
+	}
+}
+
+func (c *fakeController) VMState(name string) (monitoring.VMState, bool) {
+	for _, s := range c.VMStates() {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return monitoring.VMState{}, false
+}
+
+func (c *fakeController) StartVM(ctx context.Context, name string) error {
+	if c.startGate != nil {
+		<-c.startGate
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.VMState(name); !ok {
+		return monitoring.ErrUnknownVM
+	}
+	c.started = append(c.started, name)
+	return nil
+}
+
+func (c *fakeController) CheckVM(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked = append(c.checked, name)
+	return nil
+}
+
+func (c *fakeController) MuteVM(name string, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.muted = append(c.muted, name)
+	return nil
+}
+
+func (c *fakeController) AcknowledgeVM(name, by string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acked = append(c.acked, name+" by "+by)
+	return nil
+}
+
+func (c *fakeController) PauseVM(name string, d time.Duration, by, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = append(c.paused, fmt.Sprintf("%s %s by %s: %s", name, d, by, reason))
+	return nil
+}
+
+func (c *fakeController) ResumeVM(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.paused) == 0 {
+		return monitoring.ErrNotPaused
+	}
+	c.resumed = append(c.resumed, name)
+	return nil
+}
+
+func message(id int, chatID int64, text string) notification.Update {
+	return notification.Update{
+		UpdateID: id,
+		Message: &notification.Message{
+			MessageID: id,
+			Chat:      notification.Chat{ID: chatID},
+			From:      &notification.User{ID: 1, Username: "oncall"},
+			Text:      text,
+		},
+	}
+}
+
+func TestBot_DispatchesCommands(t *testing.T) {
+	fake := &fakeTelegram{
+		updates: []notification.Update{
+			message(10, testChatID, "/status"),
+			message(11, testChatID, "/start@watchdog_bot db"),
+			message(12, testChatID, "/check web"),
+			message(13, 999, "/start web"), // foreign chat
+			message(14, testChatID, "/start nope"),
+			message(15, testChatID, "hello"),
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := notification.NewTelegramClient("token", testChatID, nil)
+	client.SetAPIURL(server.URL)
+
+	controller := &fakeController{}
+	b := NewBot(client, controller)
+	b.pollTimeout = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+
+	// Wait for every message and for the bot to poll past the batch
+	polledPast := func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return len(fake.offsets) > 0 && fake.offsets[len(fake.offsets)-1] == 16
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for (len(fake.sentMessages()) < 5 || !polledPast()) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("bot did not stop after context cancellation")
+	}
+
+	// Four replies, and the result of the start whenever it finished
+	sent := fake.sentMessages()
+	if len(sent) != 5 {
+		t.Fatalf("expected 5 messages, got %d: %q", len(sent), sent)
+	}
+	if !strings.Contains(sent[0], "web") || !strings.Contains(sent[0], "db") {
+		t.Errorf("status reply should list all VMs, got %q", sent[0])
+	}
+	all := strings.Join(sent, "\n")
+	if !strings.Contains(all, "не найдена") {
+		t.Errorf("expected unknown VM reply, got %q", sent)
+	}
+	if !strings.Contains(all, "Запуск ВМ *db* выполнен") {
+		t.Errorf("expected the start result to be reported, got %q", sent)
+	}
+
+	if len(controller.started) != 1 || controller.started[0] != "db" {
+		t.Errorf("expected only db to be started, got %v", controller.started)
+	}
+	if len(controller.checked) != 1 || controller.checked[0] != "web" {
+		t.Errorf("expected web to be checked, got %v", controller.checked)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if last := fake.offsets[len(fake.offsets)-1]; last != 16 {
+		t.Errorf("expected offset to advance to 16, got %d", last)
+	}
+}
+
+func TestBot_HandleCallback(t *testing.T) {
+	fake := &fakeTelegram{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := notification.NewTelegramClient("token", testChatID, nil)
+	client.SetAPIURL(server.URL)
+
+	controller := &fakeController{}
+	b := NewBot(client, controller)
+
+	alert := &notification.Message{
+		MessageID:   42,
+		Chat:        notification.Chat{ID: testChatID},
+		Text:        "🚨 СБОЙ: ВМ db недоступна.",
+		ReplyMarkup: notification.NewAlertKeyboard("db"),
+	}
+	user := &notification.User{ID: 7, Username: "oncall"}
+
+	for i, data := range []string{"ack:db", "mute:db", "details:db"} {
+		b.handleUpdate(context.Background(), notification.Update{
+			UpdateID: i,
+			CallbackQuery: &notification.CallbackQuery{
+				ID:      "cb",
+				From:    user,
+				Message: alert,
+				Data:    data,
+			},
+		})
+	}
+
+	if len(controller.acked) != 1 || controller.acked[0] != "db by @oncall" {
+		t.Errorf("unexpected acknowledgements: %v", controller.acked)
+	}
+	if len(controller.muted) != 1 {
+		t.Errorf("expected db to be muted, got %v", controller.muted)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	if len(fake.answers) != 3 {
+		t.Errorf("expected every callback to be answered, got %d", len(fake.answers))
+	}
+	if len(fake.edits) != 2 {
+		t.Fatalf("expected ack and mute to edit the alert, got %d edits", len(fake.edits))
+	}
+	text := fake.edits[0]["text"].(string)
+	if !strings.HasPrefix(text, alert.Text) || !strings.Contains(text, "@oncall") {
+		t.Errorf("edited text should keep the alert and name the user, got %q", text)
+	}
+	if fake.edits[0]["reply_markup"] == nil {
+		t.Error("edit should keep the alert keyboard")
+	}
+	if len(fake.sent) != 1 || !strings.Contains(fake.sent[0], "db") {
+		t.Errorf("details should post VM status, got %q", fake.sent)
+	}
+}
+
+func TestBot_StartCommandDoesNotBlockUpdates(t *testing.T) {
+	fake := &fakeTelegram{
+		updates: []notification.Update{
+			message(10, testChatID, "/start db"),
+			message(11, testChatID, "/status"),
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := notification.NewTelegramClient("token", testChatID, nil)
+	client.SetAPIURL(server.URL)
+
+	controller := &fakeController{startGate: make(chan struct{})}
+	b := NewBot(client, controller)
+	b.pollTimeout = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+
+	// /status is answered while the start is still running
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.sentMessages()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sent := fake.sentMessages()
+	if len(sent) != 2 || !strings.Contains(sent[0], "отправлен") || !strings.Contains(sent[1], "Статус ВМ") {
+		t.Fatalf("expected the start request and status replies while the start runs, got %q", sent)
+	}
+
+	close(controller.startGate)
+	for len(fake.sentMessages()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if sent := fake.sentMessages(); len(sent) != 3 || !strings.Contains(sent[2], "выполнен") {
+		t.Errorf("expected the start result after it finished, got %q", sent)
+	}
+}
+
+func TestBot_StartButtonAnswersImmediately(t *testing.T) {
+	fake := &fakeTelegram{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := notification.NewTelegramClient("token", testChatID, nil)
+	client.SetAPIURL(server.URL)
+
+	controller := &fakeController{startGate: make(chan struct{})}
+	b := NewBot(client, controller)
+
+	alert := &notification.Message{
+		MessageID:   42,
+		Chat:        notification.Chat{ID: testChatID},
+		Text:        "🚨 СБОЙ: ВМ db недоступна.",
+		ReplyMarkup: notification.NewAlertKeyboard("db"),
+	}
+	b.handleUpdate(context.Background(), notification.Update{
+		CallbackQuery: &notification.CallbackQuery{
+			ID:      "cb",
+			From:    &notification.User{ID: 7, Username: "oncall"},
+			Message: alert,
+			Data:    "start:db",
+		},
+	})
+
+	// The button is answered while the start is still running
+	fake.mu.Lock()
+	answers, edits := len(fake.answers), len(fake.edits)
+	fake.mu.Unlock()
+	if answers != 1 || edits != 0 {
+		t.Fatalf("expected an answer before the start finished, got %d answers and %d edits", answers, edits)
+	}
+
+	close(controller.startGate)
+	b.starts.Wait()
+
+	if len(controller.started) != 1 || controller.started[0] != "db" {
+		t.Errorf("expected db to be started, got %v", controller.started)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.edits) != 1 || !strings.Contains(fake.edits[0]["text"].(string), "Запуск инициирован") {
+		t.Errorf("expected the alert to note the start, got %v", fake.edits)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text    string
+		command string
+		args    []string
+	}{
+		{"/status", "/status", nil},
+		{"/status web", "/status", []string{"web"}},
+		{"/Start@MyBot  db ", "/start", []string{"db"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			command, args := parseCommand(tt.text)
+			if command != tt.command {
+				t.Errorf("command = %q, want %q", command, tt.command)
+			}
+			if strings.Join(args, ",") != strings.Join(tt.args, ",") {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestBot_PauseResume(t *testing.T) {
+	controller := &fakeController{}
+	b := NewBot(nil, controller)
+	ctx := context.Background()
+
+	if reply := b.handleCommand(ctx, "@oncall", "/resume", []string{"db"}); !strings.Contains(reply, "не на паузе") {
+		t.Errorf("expected not-paused reply, got %q", reply)
+	}
+	if reply := b.handleCommand(ctx, "@oncall", "/pause", []string{"db", "2h", "disk", "resize"}); !strings.Contains(reply, "на паузе") {
+		t.Errorf("expected pause confirmation, got %q", reply)
+	}
+	b.handleCommand(ctx, "@oncall", "/pause", []string{"web", "upgrade"})
+	if reply := b.handleCommand(ctx, "@oncall", "/pause", []string{"web", "-1h"}); !strings.Contains(reply, "положительной") {
+		t.Errorf("expected rejected duration, got %q", reply)
+	}
+	if reply := b.handleCommand(ctx, "@oncall", "/resume", []string{"db"}); !strings.Contains(reply, "снята") {
+		t.Errorf("expected resume confirmation, got %q", reply)
+	}
+
+	want := []string{"db 2h0m0s by @oncall: disk resize", "web 1h0m0s by @oncall: upgrade"}
+	if strings.Join(controller.paused, "|") != strings.Join(want, "|") {
+		t.Errorf("paused = %q, want %q", controller.paused, want)
+	}
+	if len(controller.resumed) != 1 || controller.resumed[0] != "db" {
+		t.Errorf("resumed = %v", controller.resumed)
+	}
+}
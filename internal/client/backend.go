@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Backend controls VMs through one flavour of the Yandex Cloud API.
+// The target identifies the VM: an API Gateway base URL for YandexClient,
+// an instance ID for ComputeClient.
+type Backend interface {
+	GetVMInfo(ctx context.Context, target string) (*VMInfo, error)
+	StartVM(ctx context.Context, target string) (*StartVMResponse, error)
+	StopVM(ctx context.Context, target string) (*StopVMResponse, error)
+	RestartVM(ctx context.Context, target string) (*RestartVMResponse, error)
+}
+
+// OperationWatcher is implemented by backends whose power actions return a
+// long-running operation, which can still fail after the request was accepted
+type OperationWatcher interface {
+	WaitOperation(ctx context.Context, operationID string, interval time.Duration) (*Operation, error)
+}
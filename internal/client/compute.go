@@ -0,0 +1,326 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"golang.org/x/time/rate"
+)
+
+// codeFailedPrecondition is the gRPC code returned when e.g. starting a running instance
+const codeFailedPrecondition = 9
+
+// ComputeClient talks to the Compute Cloud API directly, authenticating
+// with a service account instead of going through an API Gateway
+type ComputeClient struct {
+	httpClient        *http.Client
+	rateLimiter       *rate.Limiter
+	tokens            *IAMTokenSource
+	endpoint          string
+	operationEndpoint string
+}
+
+// NewComputeClient creates a Compute API client
+func NewComputeClient(tokens *IAMTokenSource, endpoint, operationEndpoint string) *ComputeClient {
+	return &ComputeClient{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		// Same budget as the gateway client: 10 requests per second with burst of 20
+		rateLimiter:       rate.NewLimiter(rate.Limit(10), 20),
+		tokens:            tokens,
+		endpoint:          endpoint,
+		operationEndpoint: operationEndpoint,
+	}
+}
+
+// Instance is the subset of a Compute instance resource the watchdog uses
+type Instance struct {
+	ID                string             `json:"id"`
+	FolderID          string             `json:"folderId"`
+	Name              string             `json:"name"`
+	Labels            map[string]string  `json:"labels"`
+	Status            string             `json:"status"`
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces"`
+}
+
+// IP returns the instance's primary address, preferring the public one
+func (i *Instance) IP() string {
+	return primaryIP(i.NetworkInterfaces)
+}
+
+// Operation is a long-running Compute operation
+type Operation struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Done        bool      `json:"done"`
+	Error       *APIError `json:"error,omitempty"`
+}
+
+// APIError is an error returned by Yandex Cloud REST APIs
+type APIError struct {
+	HTTPStatus int    `json:"-"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (http %d, code %d): %s", e.HTTPStatus, e.Code, e.Message)
+}
+
+// GetInstance fetches an instance by ID
+func (c *ComputeClient) GetInstance(ctx context.Context, instanceID string) (*Instance, error) {
+	var instance Instance
+	if err := c.do(ctx, "instances.get", "GET", c.endpoint+"/compute/v1/instances/"+instanceID, nil, &instance); err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// ListInstances returns all instances in a folder, following pagination
+func (c *ComputeClient) ListInstances(ctx context.Context, folderID string) ([]Instance, error) {
+	var instances []Instance
+	pageToken := ""
+
+	for {
+		query := url.Values{}
+		query.Set("folderId", folderID)
+		query.Set("pageSize", "1000")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		var page struct {
+			Instances     []Instance `json:"instances"`
+			NextPageToken string     `json:"nextPageToken"`
+		}
+		if err := c.do(ctx, "instances.list", "GET", c.endpoint+"/compute/v1/instances?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+
+		instances = append(instances, page.Instances...)
+		if page.NextPageToken == "" {
+			return instances, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// GetVMInfo retrieves the current status and IP of an instance
+func (c *ComputeClient) GetVMInfo(ctx context.Context, instanceID string) (*VMInfo, error) {
+	instance, err := c.GetInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VMInfo{
+		Status:            normalizeStatus(instance.Status),
+		IP:                primaryIP(instance.NetworkInterfaces),
+		NetworkInterfaces: instance.NetworkInterfaces,
+	}, nil
+}
+
+// StartVM starts an instance without waiting for the operation to finish
+func (c *ComputeClient) StartVM(ctx context.Context, instanceID string) (*StartVMResponse, error) {
+	op, err := c.instanceAction(ctx, instanceID, "start")
+	if err == nil {
+		return &StartVMResponse{
+			Success:     true,
+			Message:     "VM start requested",
+			OperationID: op.ID,
+		}, nil
+	}
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return nil, err
+	}
+
+	// Starting a running instance fails with FAILED_PRECONDITION
+	if apiErr.Code == codeFailedPrecondition {
+		info, infoErr := c.GetVMInfo(ctx, instanceID)
+		if infoErr == nil && info.Status == types.StatusRunning {
+			return &StartVMResponse{
+				Success:           true,
+				WasAlreadyRunning: true,
+				IP:                info.IP,
+				Message:           "VM is already running",
+			}, nil
+		}
+	}
+
+	return &StartVMResponse{
+		Success: false,
+		Message: apiErr.Error(),
+	}, nil
+}
+
+// StopVM stops an instance without waiting for the operation to finish
+func (c *ComputeClient) StopVM(ctx context.Context, instanceID string) (*StopVMResponse, error) {
+	op, err := c.instanceAction(ctx, instanceID, "stop")
+	if err == nil {
+		return &StopVMResponse{
+			Success:     true,
+			Message:     "VM stop requested",
+			OperationID: op.ID,
+		}, nil
+	}
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return nil, err
+	}
+
+	// Stopping a stopped instance fails with FAILED_PRECONDITION
+	if apiErr.Code == codeFailedPrecondition {
+		info, infoErr := c.GetVMInfo(ctx, instanceID)
+		if infoErr == nil && info.Status == types.StatusStopped {
+			return &StopVMResponse{
+				Success:           true,
+				WasAlreadyStopped: true,
+				Message:           "VM is already stopped",
+			}, nil
+		}
+	}
+
+	return &StopVMResponse{
+		Success: false,
+		Message: apiErr.Error(),
+	}, nil
+}
+
+// RestartVM restarts an instance without waiting for the operation to finish
+func (c *ComputeClient) RestartVM(ctx context.Context, instanceID string) (*RestartVMResponse, error) {
+	op, err := c.instanceAction(ctx, instanceID, "restart")
+	if err == nil {
+		return &RestartVMResponse{
+			Success:     true,
+			Message:     "VM restart requested",
+			OperationID: op.ID,
+		}, nil
+	}
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return nil, err
+	}
+	return &RestartVMResponse{
+		Success: false,
+		Message: apiErr.Error(),
+	}, nil
+}
+
+// GetOperation fetches the current state of an operation
+func (c *ComputeClient) GetOperation(ctx context.Context, operationID string) (*Operation, error) {
+	var op Operation
+	if err := c.do(ctx, "operations.get", "GET", c.operationEndpoint+"/operations/"+operationID, nil, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// WaitOperation polls an operation until it is done or ctx is cancelled.
+// A failed operation is returned as an *APIError.
+func (c *ComputeClient) WaitOperation(ctx context.Context, operationID string, interval time.Duration) (*Operation, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		op, err := c.GetOperation(ctx, operationID)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Done {
+			if op.Error != nil {
+				return op, op.Error
+			}
+			return op, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return op, ctx.Err()
+		}
+	}
+}
+
+func (c *ComputeClient) instanceAction(ctx context.Context, instanceID, action string) (*Operation, error) {
+	var op Operation
+	endpoint := fmt.Sprintf("%s/compute/v1/instances/%s:%s", c.endpoint, instanceID, action)
+	if err := c.do(ctx, "instances."+action, "POST", endpoint, struct{}{}, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// do performs an authenticated, rate limited API call; name labels the call in metrics
+func (c *ComputeClient) do(ctx context.Context, name, method, endpoint string, payload interface{}, result interface{}) (err error) {
+	// Wait for rate limiter
+	if err := waitRateLimiter(ctx, c.rateLimiter, "compute"); err != nil {
+		return fmt.Errorf("rate limiter: %w", err)
+	}
+
+	defer func() {
+		metrics.ObserveAPICall("compute", name, err)
+	}()
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get IAM token: %w", err)
+	}
+
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{HTTPStatus: resp.StatusCode}
+		if err := json.Unmarshal(respBody, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = string(respBody)
+		}
+		return apiErr
+	}
+
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,329 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+// fakeCloud emulates the IAM token exchange and a single Compute instance
+type fakeCloud struct {
+	t         *testing.T
+	publicKey *rsa.PublicKey
+
+	mu        sync.Mutex
+	exchanges int
+	status    string
+	opPolls   int
+}
+
+func (f *fakeCloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/iam/v1/tokens" {
+		var body struct {
+			JWT string `json:"jwt"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if err := f.verifyJWT(body.JWT); err != nil {
+			f.t.Errorf("invalid JWT: %v", err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		f.exchanges++
+		writeJSON(w, map[string]interface{}{
+			"iamToken":  fmt.Sprintf("token-%d", f.exchanges),
+			"expiresAt": time.Now().Add(12 * time.Hour).Format(time.RFC3339Nano),
+		})
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]interface{}{"code": 16, "message": "unauthenticated"})
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/compute/v1/instances":
+		if r.URL.Query().Get("folderId") != "folder1" {
+			writeJSON(w, map[string]interface{}{})
+			return
+		}
+		// Two pages to exercise pagination
+		if r.URL.Query().Get("pageToken") == "" {
+			writeJSON(w, map[string]interface{}{
+				"instances":     []interface{}{map[string]interface{}{"id": "vm1", "name": "web"}},
+				"nextPageToken": "p2",
+			})
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"instances": []interface{}{map[string]interface{}{"id": "vm2", "name": "db", "labels": map[string]string{"watchdog": "enabled"}}},
+		})
+	case r.Method == "GET" && r.URL.Path == "/compute/v1/instances/vm1":
+		writeJSON(w, map[string]interface{}{
+			"id":     "vm1",
+			"name":   "web",
+			"status": f.status,
+			"networkInterfaces": []interface{}{
+				map[string]interface{}{
+					"primaryV4Address": map[string]interface{}{
+						"address":     "10.0.0.5",
+						"oneToOneNat": map[string]interface{}{"address": "51.250.1.1"},
+					},
+				},
+			},
+		})
+	case r.Method == "POST" && r.URL.Path == "/compute/v1/instances/vm1:start":
+		if f.status == "RUNNING" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]interface{}{"code": 9, "message": "Instance is already running"})
+			return
+		}
+		f.status = "STARTING"
+		writeJSON(w, map[string]interface{}{"id": "op1", "done": false})
+	case r.Method == "POST" && r.URL.Path == "/compute/v1/instances/vm1:stop":
+		if f.status == "STOPPED" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]interface{}{"code": 9, "message": "Instance is already stopped"})
+			return
+		}
+		f.status = "STOPPING"
+		writeJSON(w, map[string]interface{}{"id": "op2", "done": false})
+	case r.Method == "POST" && r.URL.Path == "/compute/v1/instances/vm1:restart":
+		if f.status != "RUNNING" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]interface{}{"code": 9, "message": "Instance is not running"})
+			return
+		}
+		writeJSON(w, map[string]interface{}{"id": "op3", "done": false})
+	case r.Method == "GET" && r.URL.Path == "/operations/op1":
+		f.opPolls++
+		writeJSON(w, map[string]interface{}{"id": "op1", "done": f.opPolls >= 2})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]interface{}{"code": 5, "message": "not found"})
+	}
+}
+
+func (f *fakeCloud) verifyJWT(jwt string) error {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected 3 parts, got %d", len(parts))
+	}
+
+	var header map[string]string
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	_ = json.Unmarshal(headerJSON, &header)
+	if header["alg"] != "PS256" || header["kid"] != "key-id" {
+		return fmt.Errorf("unexpected header %v", header)
+	}
+
+	var claims map[string]interface{}
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	_ = json.Unmarshal(claimsJSON, &claims)
+	if claims["iss"] != "sa-id" || claims["aud"] != iamAudience {
+		return fmt.Errorf("unexpected claims %v", claims)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	return rsa.VerifyPSS(f.publicKey, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	})
+}
+
+func (f *fakeCloud) exchangeCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.exchanges
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestComputeClient(t *testing.T, status string) (*ComputeClient, *fakeCloud) {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := "PLEASE DO NOT REMOVE THIS LINE! Yandex.Cloud SA Key ID <key-id>\n" +
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	cloud := &fakeCloud{t: t, publicKey: &privateKey.PublicKey, status: status}
+	server := httptest.NewServer(cloud)
+	t.Cleanup(server.Close)
+
+	tokens, err := NewIAMTokenSource(&ServiceAccountKey{
+		ID:               "key-id",
+		ServiceAccountID: "sa-id",
+		PrivateKey:       keyPEM,
+	}, server.URL)
+	if err != nil {
+		t.Fatalf("NewIAMTokenSource: %v", err)
+	}
+
+	return NewComputeClient(tokens, server.URL, server.URL), cloud
+}
+
+func TestComputeClient_GetVMInfo(t *testing.T) {
+	c, cloud := newTestComputeClient(t, "RUNNING")
+	ctx := context.Background()
+
+	info, err := c.GetVMInfo(ctx, "vm1")
+	if err != nil {
+		t.Fatalf("GetVMInfo: %v", err)
+	}
+	if info.Status != types.StatusRunning || info.IP != "51.250.1.1" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	// The cached token is reused
+	if _, err := c.GetVMInfo(ctx, "vm1"); err != nil {
+		t.Fatalf("GetVMInfo: %v", err)
+	}
+	if n := cloud.exchangeCount(); n != 1 {
+		t.Errorf("expected 1 token exchange, got %d", n)
+	}
+
+	if _, err := c.GetVMInfo(ctx, "missing"); err == nil {
+		t.Error("expected error for missing instance")
+	} else if apiErr, ok := err.(*APIError); !ok || apiErr.HTTPStatus != http.StatusNotFound {
+		t.Errorf("expected 404 APIError, got %v", err)
+	}
+}
+
+func TestComputeClient_StartVM(t *testing.T) {
+	c, _ := newTestComputeClient(t, "STOPPED")
+	ctx := context.Background()
+
+	resp, err := c.StartVM(ctx, "vm1")
+	if err != nil {
+		t.Fatalf("StartVM: %v", err)
+	}
+	if !resp.Success || resp.WasAlreadyRunning || resp.OperationID != "op1" {
+		t.Errorf("unexpected start response: %+v", resp)
+	}
+
+	op, err := c.WaitOperation(ctx, resp.OperationID, 10*time.Millisecond)
+	if err != nil || !op.Done {
+		t.Fatalf("WaitOperation() = %+v, %v", op, err)
+	}
+}
+
+func TestComputeClient_StartVMAlreadyRunning(t *testing.T) {
+	c, _ := newTestComputeClient(t, "RUNNING")
+
+	resp, err := c.StartVM(context.Background(), "vm1")
+	if err != nil {
+		t.Fatalf("StartVM: %v", err)
+	}
+	if !resp.Success || !resp.WasAlreadyRunning || resp.IP != "51.250.1.1" {
+		t.Errorf("unexpected start response: %+v", resp)
+	}
+}
+
+func TestComputeClient_StopVM(t *testing.T) {
+	c, _ := newTestComputeClient(t, "RUNNING")
+	ctx := context.Background()
+
+	resp, err := c.StopVM(ctx, "vm1")
+	if err != nil {
+		t.Fatalf("StopVM: %v", err)
+	}
+	if !resp.Success || resp.WasAlreadyStopped || resp.OperationID != "op2" {
+		t.Errorf("unexpected stop response: %+v", resp)
+	}
+
+	stopped, _ := newTestComputeClient(t, "STOPPED")
+	resp, err = stopped.StopVM(ctx, "vm1")
+	if err != nil {
+		t.Fatalf("StopVM: %v", err)
+	}
+	if !resp.Success || !resp.WasAlreadyStopped {
+		t.Errorf("unexpected stop response for stopped VM: %+v", resp)
+	}
+}
+
+func TestComputeClient_RestartVM(t *testing.T) {
+	c, _ := newTestComputeClient(t, "RUNNING")
+	ctx := context.Background()
+
+	resp, err := c.RestartVM(ctx, "vm1")
+	if err != nil {
+		t.Fatalf("RestartVM: %v", err)
+	}
+	if !resp.Success || resp.OperationID != "op3" {
+		t.Errorf("unexpected restart response: %+v", resp)
+	}
+
+	stopped, _ := newTestComputeClient(t, "STOPPED")
+	resp, err = stopped.RestartVM(ctx, "vm1")
+	if err != nil {
+		t.Fatalf("RestartVM: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Message, "not running") {
+		t.Errorf("expected restart of a stopped VM to fail, got %+v", resp)
+	}
+}
+
+func TestComputeClient_ListInstances(t *testing.T) {
+	c, _ := newTestComputeClient(t, "RUNNING")
+
+	instances, err := c.ListInstances(context.Background(), "folder1")
+	if err != nil {
+		t.Fatalf("ListInstances: %v", err)
+	}
+	if len(instances) != 2 || instances[0].ID != "vm1" || instances[1].Labels["watchdog"] != "enabled" {
+		t.Errorf("unexpected instances: %+v", instances)
+	}
+}
+
+func TestIAMTokenSource_RefreshesBeforeExpiry(t *testing.T) {
+	c, cloud := newTestComputeClient(t, "RUNNING")
+	ctx := context.Background()
+
+	first, err := c.tokens.Token(ctx)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	// Move the clock to within the refresh margin of expiry
+	c.tokens.now = func() time.Time { return time.Now().Add(11*time.Hour + 30*time.Minute) }
+
+	second, err := c.tokens.Token(ctx)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if n := cloud.exchangeCount(); first == second || n != 2 {
+		t.Errorf("expected a refreshed token, got %q then %q (%d exchanges)", first, second, n)
+	}
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
+)
+
+const (
+	// iamAudience is the JWT audience expected by the IAM token exchange,
+	// independent of the endpoint actually used
+	iamAudience = "https://iam.api.cloud.yandex.net/iam/v1/tokens"
+	// jwtLifetime is how long a signed JWT is accepted for exchange (max 1h)
+	jwtLifetime = time.Hour
+	// tokenRefreshMargin refreshes IAM tokens (valid for 12h) well before expiry
+	tokenRefreshMargin = time.Hour
+)
+
+// ServiceAccountKey is an authorized key as produced by `yc iam key create`
+type ServiceAccountKey struct {
+	ID               string `json:"id"`
+	ServiceAccountID string `json:"service_account_id"`
+	PrivateKey       string `json:"private_key"`
+}
+
+// LoadServiceAccountKey reads an authorized key JSON file
+func LoadServiceAccountKey(path string) (*ServiceAccountKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account key: %w", err)
+	}
+
+	var key ServiceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %w", err)
+	}
+
+	if key.ID == "" || key.ServiceAccountID == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("service account key must contain id, service_account_id and private_key")
+	}
+
+	return &key, nil
+}
+
+// IAMTokenSource exchanges a service account key for IAM tokens and caches them
+type IAMTokenSource struct {
+	key        *ServiceAccountKey
+	privateKey *rsa.PrivateKey
+	endpoint   string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	now       func() time.Time
+}
+
+// NewIAMTokenSource creates a token source for the key, using the IAM API at endpoint
+func NewIAMTokenSource(key *ServiceAccountKey, endpoint string) (*IAMTokenSource, error) {
+	privateKey, err := parsePrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IAMTokenSource{
+		key:        key,
+		privateKey: privateKey,
+		endpoint:   endpoint,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		now: time.Now,
+	}, nil
+}
+
+// Token returns a valid IAM token, refreshing it if it is close to expiry
+func (s *IAMTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Add(tokenRefreshMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	token, expiresAt, err := s.exchange(ctx)
+	if err != nil {
+		// A token that has not expired yet is still usable if the refresh fails
+		if s.token != "" && s.now().Before(s.expiresAt) {
+			return s.token, nil
+		}
+		return "", err
+	}
+
+	s.token = token
+	s.expiresAt = expiresAt
+	return s.token, nil
+}
+
+func (s *IAMTokenSource) exchange(ctx context.Context) (token string, expiresAt time.Time, err error) {
+	jwt, err := s.signJWT(s.now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	defer func() {
+		metrics.ObserveAPICall("iam", "tokens.create", err)
+	}()
+
+	body, err := json.Marshal(map[string]string{"jwt": jwt})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.endpoint+"/iam/v1/tokens", bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", time.Time{}, fmt.Errorf("IAM token exchange failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		IAMToken  string    `json:"iamToken"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.IAMToken, result.ExpiresAt, nil
+}
+
+// signJWT builds a PS256 JWT as required by the IAM token exchange
+func (s *IAMTokenSource) signJWT(now time.Time) (string, error) {
+	header := map[string]string{
+		"typ": "JWT",
+		"alg": "PS256",
+		"kid": s.key.ID,
+	}
+	claims := map[string]interface{}{
+		"aud": iamAudience,
+		"iss": s.key.ServiceAccountID,
+		"iat": now.Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPSS(rand.Reader, s.privateKey, crypto.SHA256, digest[:], &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey decodes the PEM key from an authorized key file.
+// Yandex prefixes the PEM block with a comment line, which pem.Decode skips.
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("service account private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("service account private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+	return key, nil
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
 	"github.com/fxfuren/yandex-watcher-bot/internal/types"
 	"golang.org/x/time/rate"
 )
@@ -89,15 +90,38 @@ type StartVMResponse struct {
 	Message string
 	IP      string
 	WasAlreadyRunning bool
+	// OperationID is set by backends that return a long-running operation
+	OperationID string
+}
+
+// StopVMResponse contains the response from stop VM API
+type StopVMResponse struct {
+	Success           bool
+	Message           string
+	WasAlreadyStopped bool
+	// OperationID is set by backends that return a long-running operation
+	OperationID string
+}
+
+// RestartVMResponse contains the response from restart VM API
+type RestartVMResponse struct {
+	Success bool
+	Message string
+	// OperationID is set by backends that return a long-running operation
+	OperationID string
 }
 
 // GetVMInfo retrieves the current status and IP of a VM
-func (c *YandexClient) GetVMInfo(ctx context.Context, baseURL string) (*VMInfo, error) {
+func (c *YandexClient) GetVMInfo(ctx context.Context, baseURL string) (info *VMInfo, err error) {
 	// Wait for rate limiter
-	if err := c.rateLimiter.Wait(ctx); err != nil {
+	if err := waitRateLimiter(ctx, c.rateLimiter, "gateway"); err != nil {
 		return nil, fmt.Errorf("rate limiter: %w", err)
 	}
 
+	defer func() {
+		metrics.ObserveAPICall("gateway", "info", err)
+	}()
+
 	url := baseURL + "/info"
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -125,34 +149,101 @@ func (c *YandexClient) GetVMInfo(ctx context.Context, baseURL string) (*VMInfo,
 	}
 
 	// Normalize status: "RUNNING" -> "Running", "STOPPED" -> "Stopped"
-	info := &VMInfo{
+	info = &VMInfo{
 		Status:            normalizeStatus(rawInfo.Status),
 		NetworkInterfaces: rawInfo.NetworkInterfaces,
 	}
 
 	// Extract IP address
-	if len(info.NetworkInterfaces) > 0 {
-		primary := info.NetworkInterfaces[0].PrimaryV4Address
-		// Prefer public IP
-		if primary.OneToOneNat != nil && primary.OneToOneNat.Address != "" {
-			info.IP = primary.OneToOneNat.Address
-		} else if primary.Address != "" {
-			info.IP = primary.Address
-		}
-	}
+	info.IP = primaryIP(info.NetworkInterfaces)
 
 	return info, nil
 }
 
+// primaryIP returns the address of the first interface, preferring the public one
+func primaryIP(interfaces []NetworkInterface) string {
+	if len(interfaces) == 0 {
+		return ""
+	}
+
+	primary := interfaces[0].PrimaryV4Address
+	if primary.OneToOneNat != nil && primary.OneToOneNat.Address != "" {
+		return primary.OneToOneNat.Address
+	}
+	return primary.Address
+}
+
 // StartVM attempts to start a VM
 func (c *YandexClient) StartVM(ctx context.Context, baseURL string) (*StartVMResponse, error) {
+	reply, err := c.powerAction(ctx, baseURL, "start", "RUNNING")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case reply.accepted:
+		return &StartVMResponse{Success: true, Message: "VM started successfully"}, nil
+	case reply.alreadyDone:
+		return &StartVMResponse{Success: true, WasAlreadyRunning: true, IP: reply.ip, Message: "VM is already running"}, nil
+	}
+	return &StartVMResponse{Message: reply.message}, nil
+}
+
+// StopVM attempts to stop a VM through the gateway's /stop route
+func (c *YandexClient) StopVM(ctx context.Context, baseURL string) (*StopVMResponse, error) {
+	reply, err := c.powerAction(ctx, baseURL, "stop", "STOPPED")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case reply.accepted:
+		return &StopVMResponse{Success: true, Message: "VM stop requested"}, nil
+	case reply.alreadyDone:
+		return &StopVMResponse{Success: true, WasAlreadyStopped: true, Message: "VM is already stopped"}, nil
+	}
+	return &StopVMResponse{Message: reply.message}, nil
+}
+
+// RestartVM attempts to restart a VM through the gateway's /restart route
+func (c *YandexClient) RestartVM(ctx context.Context, baseURL string) (*RestartVMResponse, error) {
+	reply, err := c.powerAction(ctx, baseURL, "restart", "")
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.accepted {
+		return &RestartVMResponse{Success: true, Message: "VM restart requested"}, nil
+	}
+	return &RestartVMResponse{Message: reply.message}, nil
+}
+
+// gatewayReply is the outcome of a power action the gateway answered
+type gatewayReply struct {
+	accepted    bool   // the action was started
+	alreadyDone bool   // the VM already has the status the action leads to
+	ip          string // reported along with alreadyDone
+	message     string // why the action was refused
+}
+
+// powerAction posts to one of the gateway's power routes. A refusal with code 9
+// (FAILED_PRECONDITION) carries the status that blocked the action; if that is
+// doneStatus, the VM is already where the action would take it.
+func (c *YandexClient) powerAction(ctx context.Context, baseURL, action, doneStatus string) (reply *gatewayReply, err error) {
 	// Wait for rate limiter
-	if err := c.rateLimiter.Wait(ctx); err != nil {
+	if err := waitRateLimiter(ctx, c.rateLimiter, "gateway"); err != nil {
 		return nil, fmt.Errorf("rate limiter: %w", err)
 	}
 
-	url := baseURL + "/start"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	defer func() {
+		if err == nil && !reply.accepted && !reply.alreadyDone {
+			metrics.ObserveAPICall("gateway", action, fmt.Errorf("%s", reply.message))
+			return
+		}
+		metrics.ObserveAPICall("gateway", action, err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/"+action, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -163,16 +254,11 @@ func (c *YandexClient) StartVM(ctx context.Context, baseURL string) (*StartVMRes
 	}
 	defer resp.Body.Close()
 
-	result := &StartVMResponse{}
-
-	// Status 200 means VM started successfully
+	// Status 200 means the action was started
 	if resp.StatusCode == http.StatusOK {
-		result.Success = true
-		result.Message = "VM started successfully"
-		return result, nil
+		return &gatewayReply{accepted: true}, nil
 	}
 
-	// Try to parse error response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
@@ -183,27 +269,23 @@ func (c *YandexClient) StartVM(ctx context.Context, baseURL string) (*StartVMRes
 		Message string `json:"message"`
 		IP      string `json:"ip"`
 	}
-
 	if err := json.Unmarshal(body, &errorResp); err != nil {
 		// Not JSON, return raw error
-		result.Success = false
-		result.Message = fmt.Sprintf("API error (%d): %s", resp.StatusCode, string(body))
-		return result, nil
+		return &gatewayReply{message: fmt.Sprintf("API error (%d): %s", resp.StatusCode, string(body))}, nil
 	}
 
-	// Code 9 with "RUNNING" message means VM is already running
-	if errorResp.Code == 9 && errorResp.Message == "RUNNING" {
-		result.Success = true
-		result.WasAlreadyRunning = true
-		result.IP = errorResp.IP
-		result.Message = "VM is already running"
-		return result, nil
+	if doneStatus != "" && errorResp.Code == 9 && errorResp.Message == doneStatus {
+		return &gatewayReply{alreadyDone: true, ip: errorResp.IP}, nil
 	}
+	return &gatewayReply{message: fmt.Sprintf("API error (%d): %s", resp.StatusCode, errorResp.Message)}, nil
+}
 
-	// Other error codes
-	result.Success = false
-	result.Message = fmt.Sprintf("API error (%d): %s", resp.StatusCode, errorResp.Message)
-	return result, nil
+// waitRateLimiter blocks on the limiter and records how long it took
+func waitRateLimiter(ctx context.Context, limiter *rate.Limiter, backend string) error {
+	start := time.Now()
+	err := limiter.Wait(ctx)
+	metrics.ObserveRateLimiterWait(backend, time.Since(start))
+	return err
 }
 
 // WithRetry wraps a function with exponential backoff retry logic
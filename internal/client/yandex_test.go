@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newFakeGateway answers every power route with the given status and body
+func newFakeGateway(t *testing.T, status int, body map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("expected POST, got %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+		if body != nil {
+			writeJSON(w, body)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestYandexClient_StartVM(t *testing.T) {
+	c := NewYandexClient()
+	ctx := context.Background()
+
+	resp, err := c.StartVM(ctx, newFakeGateway(t, http.StatusOK, nil).URL)
+	if err != nil || !resp.Success || resp.WasAlreadyRunning {
+		t.Errorf("expected a started VM, got %+v, %v", resp, err)
+	}
+
+	running := newFakeGateway(t, http.StatusBadRequest, map[string]interface{}{"code": 9, "message": "RUNNING", "ip": "10.0.0.5"})
+	resp, err = c.StartVM(ctx, running.URL)
+	if err != nil || !resp.Success || !resp.WasAlreadyRunning || resp.IP != "10.0.0.5" {
+		t.Errorf("expected an already running VM, got %+v, %v", resp, err)
+	}
+
+	stopping := newFakeGateway(t, http.StatusBadRequest, map[string]interface{}{"code": 9, "message": "STOPPING"})
+	resp, err = c.StartVM(ctx, stopping.URL)
+	if err != nil || resp.Success || !strings.Contains(resp.Message, "STOPPING") {
+		t.Errorf("expected a refused start, got %+v, %v", resp, err)
+	}
+}
+
+func TestYandexClient_StopVM(t *testing.T) {
+	c := NewYandexClient()
+	ctx := context.Background()
+
+	resp, err := c.StopVM(ctx, newFakeGateway(t, http.StatusOK, nil).URL)
+	if err != nil || !resp.Success || resp.WasAlreadyStopped {
+		t.Errorf("expected a stop request, got %+v, %v", resp, err)
+	}
+
+	stopped := newFakeGateway(t, http.StatusBadRequest, map[string]interface{}{"code": 9, "message": "STOPPED"})
+	resp, err = c.StopVM(ctx, stopped.URL)
+	if err != nil || !resp.Success || !resp.WasAlreadyStopped {
+		t.Errorf("expected an already stopped VM, got %+v, %v", resp, err)
+	}
+}
+
+func TestYandexClient_RestartVM(t *testing.T) {
+	c := NewYandexClient()
+	ctx := context.Background()
+
+	resp, err := c.RestartVM(ctx, newFakeGateway(t, http.StatusOK, nil).URL)
+	if err != nil || !resp.Success {
+		t.Errorf("expected a restart request, got %+v, %v", resp, err)
+	}
+
+	// Without a status to reach, code 9 is a refusal like any other
+	stopped := newFakeGateway(t, http.StatusBadRequest, map[string]interface{}{"code": 9, "message": "STOPPED"})
+	resp, err = c.RestartVM(ctx, stopped.URL)
+	if err != nil || resp.Success || resp.Message != "API error (400): STOPPED" {
+		t.Errorf("expected a refused restart, got %+v, %v", resp, err)
+	}
+}
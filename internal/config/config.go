@@ -6,6 +6,9 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/fxfuren/yandex-watcher-bot/internal/probe"
+	"github.com/fxfuren/yandex-watcher-bot/internal/schedule"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,14 +21,192 @@ type Config struct {
 	MaxCheckInterval   time.Duration `yaml:"-"`
 	APIWorkerPoolSize  int           `yaml:"-"`
 	TelegramWorkers    int           `yaml:"-"`
+	TelegramCommands   bool          `yaml:"-"`
+	TelegramLive       bool          `yaml:"-"`
+	StateDir           string        `yaml:"-"`
+	NotificationMaxAge time.Duration `yaml:"-"`
+	ServiceAccountKey  string        `yaml:"-"`
+	IAMEndpoint        string        `yaml:"-"`
+	ComputeEndpoint    string        `yaml:"-"`
+	OperationEndpoint  string        `yaml:"-"`
+	HTTPEnabled        bool          `yaml:"-"`
+	HTTPAddr           string        `yaml:"-"`
+	HTTPAPIToken       string        `yaml:"-"`
+	Notifiers          Notifiers     `yaml:"notifiers"`
+	Discovery          Discovery     `yaml:"discovery"`
+	Policy             Policy        `yaml:"policy"`
 	VMs                []VM          `yaml:"vms"`
 }
 
-// VM represents a virtual machine configuration
+// Discovery configures automatic monitoring of instances in a folder
+type Discovery struct {
+	Enabled  bool   `yaml:"enabled"`
+	FolderID string `yaml:"folder_id"`
+	// Labels selects instances; a value of "*" matches any value of the label
+	Labels   map[string]string `yaml:"labels"`
+	Interval time.Duration     `yaml:"interval"`
+}
+
+// Notifiers configures the notification backends
+type Notifiers struct {
+	Telegram TelegramNotifier `yaml:"telegram"`
+	Webhook  WebhookNotifier  `yaml:"webhook"`
+	Slack    SlackNotifier    `yaml:"slack"`
+	Email    EmailNotifier    `yaml:"email"`
+}
+
+// NotifierBase holds the settings shared by all backends
+type NotifierBase struct {
+	Enabled bool `yaml:"enabled"`
+	// MinPriority is one of low, normal, critical (default: low)
+	MinPriority string `yaml:"min_priority"`
+	// EscalationOnly limits the backend to escalation steps that name it
+	EscalationOnly bool `yaml:"escalation_only"`
+}
+
+// TelegramNotifier configures alerts to the Telegram group (enabled by default)
+type TelegramNotifier struct {
+	NotifierBase `yaml:",inline"`
+}
+
+// WebhookNotifier configures a generic JSON webhook
+type WebhookNotifier struct {
+	NotifierBase `yaml:",inline"`
+	URL          string            `yaml:"url"`
+	Headers      map[string]string `yaml:"headers,omitempty"`
+}
+
+// SlackNotifier configures a Slack-compatible incoming webhook
+type SlackNotifier struct {
+	NotifierBase `yaml:",inline"`
+	WebhookURL   string `yaml:"webhook_url"`
+}
+
+// EmailNotifier configures SMTP email alerts
+type EmailNotifier struct {
+	NotifierBase `yaml:",inline"`
+	Host         string `yaml:"host"`
+	Port         int    `yaml:"port"`
+	Username     string `yaml:"username"`
+	// Password may be left empty and supplied via SMTP_PASSWORD instead
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// VM represents a virtual machine configuration.
+// A VM is controlled either through an API Gateway (URL) or directly
+// through the Compute API by instance ID.
 type VM struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
-	IP   string `yaml:"ip,omitempty"`
+	Name       string `yaml:"name"`
+	URL        string `yaml:"url,omitempty"`
+	InstanceID string `yaml:"instance_id,omitempty"`
+	IP         string `yaml:"ip,omitempty"`
+	// Policy overrides the global policy for this VM
+	Policy Policy `yaml:"policy,omitempty"`
+	// Maintenance lists recurring windows in which the VM may be down
+	Maintenance []MaintenanceWindow `yaml:"maintenance,omitempty"`
+	// DesiredState is running (default), stopped or unmanaged (observe only)
+	DesiredState types.DesiredState `yaml:"desired_state,omitempty"`
+	// Schedule keeps the VM running only during its hours and stops it outside them
+	Schedule *PowerSchedule `yaml:"schedule,omitempty"`
+	// Probes check the VM's services; the VM is healthy only when enough of them pass
+	Probes []Probe `yaml:"probes,omitempty"`
+	// ProbesRequired is all (default), any or how many probes must pass
+	ProbesRequired string `yaml:"probes_required,omitempty"`
+}
+
+// Desired returns the VM's desired state, running unless configured otherwise
+func (v *VM) Desired() types.DesiredState {
+	if v.DesiredState == "" {
+		return types.DesiredRunning
+	}
+	return v.DesiredState
+}
+
+// MaintenanceWindow starts whenever Cron fires and lasts Duration.
+// While it is active the VM is not started and failures are not alerted.
+type MaintenanceWindow struct {
+	Cron     string        `yaml:"cron"`
+	Duration time.Duration `yaml:"duration"`
+	// Timezone is an IANA name such as Europe/Moscow (default: local time)
+	Timezone string `yaml:"timezone,omitempty"`
+}
+
+// Window parses the maintenance window
+func (w MaintenanceWindow) Window() (*schedule.Window, error) {
+	return schedule.NewWindow(w.Cron, w.Duration, w.Timezone)
+}
+
+// PowerSchedule is a weekly on-schedule, e.g. mon-fri 08:00-20:00
+type PowerSchedule struct {
+	// Days lists weekdays and ranges, e.g. "mon-fri" or "mon,wed,fri" (default: every day)
+	Days string `yaml:"days,omitempty"`
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	// Timezone is an IANA name such as Europe/Moscow (default: local time)
+	Timezone string `yaml:"timezone,omitempty"`
+}
+
+// Hours parses the schedule
+func (s PowerSchedule) Hours() (*schedule.Hours, error) {
+	return schedule.NewHours(s.Days, s.From, s.To, s.Timezone)
+}
+
+// Probe is a TCP or HTTP(S) check of a service on the VM.
+// Exactly one of TCP and HTTP is set; {ip} in either is replaced with the VM's IP.
+type Probe struct {
+	Name string `yaml:"name,omitempty"`
+	// TCP is a host:port that must accept connections
+	TCP string `yaml:"tcp,omitempty"`
+	// HTTP is a URL that must answer a GET request
+	HTTP string `yaml:"http,omitempty"`
+	// ExpectStatus is the required HTTP status (default: any 2xx)
+	ExpectStatus int `yaml:"expect_status,omitempty"`
+	// ExpectBody must occur in the HTTP response body
+	ExpectBody string `yaml:"expect_body,omitempty"`
+	// MaxLatency fails a probe that answers slower than this
+	MaxLatency time.Duration `yaml:"max_latency,omitempty"`
+	Timeout    time.Duration `yaml:"timeout,omitempty"`
+	// Insecure skips TLS certificate verification
+	Insecure bool `yaml:"insecure,omitempty"`
+	// FailureThreshold is how many runs in a row must fail before the probe is down (default 1)
+	FailureThreshold int `yaml:"failure_threshold,omitempty"`
+}
+
+// Probe builds the probe
+func (p Probe) Probe() (*probe.Probe, error) {
+	spec := probe.Spec{
+		Name:             p.Name,
+		ExpectStatus:     p.ExpectStatus,
+		ExpectBody:       p.ExpectBody,
+		MaxLatency:       p.MaxLatency,
+		Timeout:          p.Timeout,
+		Insecure:         p.Insecure,
+		FailureThreshold: p.FailureThreshold,
+	}
+	switch {
+	case p.TCP != "" && p.HTTP != "":
+		return nil, fmt.Errorf("only one of tcp and http may be set")
+	case p.TCP != "":
+		spec.Kind, spec.Target = probe.KindTCP, p.TCP
+	case p.HTTP != "":
+		spec.Kind, spec.Target = probe.KindHTTP, p.HTTP
+	default:
+		return nil, fmt.Errorf("either tcp or http is required")
+	}
+	return probe.New(spec)
+}
+
+// UsesComputeAPI reports whether the VM is managed through the Compute API
+func (v *VM) UsesComputeAPI() bool {
+	return v.InstanceID != ""
+}
+
+// HTTPSettings reads the HTTP endpoint settings. They are available without
+// Load so health endpoints can report a configuration that fails to load.
+func HTTPSettings() (enabled bool, addr string) {
+	return getEnvBool("HTTP_ENABLED", true), getEnvString("HTTP_ADDR", ":8080")
 }
 
 // Load reads configuration from environment and YAML file
@@ -35,7 +216,20 @@ func Load(yamlPath string) (*Config, error) {
 		MaxCheckInterval:  getEnvDuration("MAX_CHECK_INTERVAL", 60*time.Second),
 		APIWorkerPoolSize: getEnvInt("API_WORKER_POOL_SIZE", 10),
 		TelegramWorkers:   getEnvInt("TELEGRAM_WORKERS", 3),
+		TelegramCommands:  getEnvBool("TELEGRAM_COMMANDS", true),
+		TelegramLive:      getEnvBool("TELEGRAM_LIVE_MESSAGES", false),
+		StateDir:          getEnvString("STATE_DIR", "data"),
+		ServiceAccountKey: os.Getenv("YC_SA_KEY_FILE"),
+		IAMEndpoint:       getEnvString("YC_IAM_ENDPOINT", "https://iam.api.cloud.yandex.net"),
+		ComputeEndpoint:   getEnvString("YC_COMPUTE_ENDPOINT", "https://compute.api.cloud.yandex.net"),
+		OperationEndpoint: getEnvString("YC_OPERATION_ENDPOINT", "https://operation.api.cloud.yandex.net"),
 	}
+	cfg.HTTPEnabled, cfg.HTTPAddr = HTTPSettings()
+	cfg.HTTPAPIToken = os.Getenv("HTTP_API_TOKEN")
+	cfg.NotificationMaxAge = getEnvDuration("NOTIFICATION_MAX_AGE", 24*time.Hour)
+	cfg.Notifiers.Telegram.Enabled = true
+	cfg.Notifiers.Email.Port = 587
+	cfg.Discovery.Interval = 5 * time.Minute
 
 	// Bot token (required)
 	cfg.BotToken = os.Getenv("BOT_TOKEN")
@@ -63,15 +257,23 @@ func Load(yamlPath string) (*Config, error) {
 		cfg.TopicID = &topicID
 	}
 
-	// Load VMs from YAML
-	if err := cfg.loadVMs(yamlPath); err != nil {
-		return nil, fmt.Errorf("failed to load VMs: %w", err)
+	// Load VMs and other settings from YAML
+	if err := cfg.loadYAML(yamlPath); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", yamlPath, err)
+	}
+
+	if cfg.Notifiers.Email.Password == "" {
+		cfg.Notifiers.Email.Password = os.Getenv("SMTP_PASSWORD")
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
-func (c *Config) loadVMs(path string) error {
+func (c *Config) loadYAML(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -81,27 +283,187 @@ func (c *Config) loadVMs(path string) error {
 		return err
 	}
 
-	var yamlConfig struct {
-		VMs []VM `yaml:"vms"`
+	// Fields tagged yaml:"-" come from the environment and are left untouched
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	if err := yaml.Unmarshal(data, &yamlConfig); err != nil {
-		return fmt.Errorf("failed to parse YAML: %w", err)
+	return nil
+}
+
+func (c *Config) validate() error {
+	if err := c.Policy.normalize(); err != nil {
+		return fmt.Errorf("policy: %w", err)
+	}
+
+	if err := c.validateVMs(); err != nil {
+		return err
+	}
+
+	if c.Discovery.Enabled {
+		if c.Discovery.FolderID == "" {
+			return fmt.Errorf("discovery: folder_id is required")
+		}
+		if c.ServiceAccountKey == "" {
+			return fmt.Errorf("discovery: requires YC_SA_KEY_FILE")
+		}
+		if c.Discovery.Interval < time.Minute {
+			return fmt.Errorf("discovery: interval must be at least 1m")
+		}
+	}
+
+	n := c.Notifiers
+	for name, p := range map[string]string{
+		"telegram": n.Telegram.MinPriority,
+		"webhook":  n.Webhook.MinPriority,
+		"slack":    n.Slack.MinPriority,
+		"email":    n.Email.MinPriority,
+	} {
+		switch p {
+		case "", "low", "normal", "critical":
+		default:
+			return fmt.Errorf("notifiers.%s: invalid min_priority %q", name, p)
+		}
+	}
+
+	if n.Webhook.Enabled && n.Webhook.URL == "" {
+		return fmt.Errorf("notifiers.webhook: url is required")
+	}
+	if n.Slack.Enabled && n.Slack.WebhookURL == "" {
+		return fmt.Errorf("notifiers.slack: webhook_url is required")
+	}
+	if n.Email.Enabled && (n.Email.Host == "" || n.Email.From == "" || len(n.Email.To) == 0) {
+		return fmt.Errorf("notifiers.email: host, from and to are required")
+	}
+
+	if err := c.validateEscalationChannels(); err != nil {
+		return err
 	}
 
-	c.VMs = yamlConfig.VMs
 	return nil
 }
 
-// SaveVMs writes the current VM configuration back to the YAML file
+// validateEscalationChannels checks that escalation steps only name enabled notifiers
+func (c *Config) validateEscalationChannels() error {
+	n := c.Notifiers
+	enabled := map[string]bool{
+		"telegram": n.Telegram.Enabled,
+		"webhook":  n.Webhook.Enabled,
+		"slack":    n.Slack.Enabled,
+		"email":    n.Email.Enabled,
+	}
+
+	check := func(prefix string, steps []EscalationStep) error {
+		for i, step := range steps {
+			for _, name := range step.Channels {
+				if !enabled[name] {
+					return fmt.Errorf("%spolicy: escalation[%d]: channel %q is not enabled in notifiers", prefix, i, name)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := check("", c.Policy.Escalation); err != nil {
+		return err
+	}
+	for _, vm := range c.VMs {
+		if err := check(fmt.Sprintf("vm %q: ", vm.Name), vm.Policy.Escalation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateVMs() error {
+	seen := make(map[string]bool, len(c.VMs))
+	for i, vm := range c.VMs {
+		if vm.Name == "" {
+			return fmt.Errorf("vms[%d]: name is required", i)
+		}
+		if seen[vm.Name] {
+			return fmt.Errorf("vms[%d]: duplicate name %q", i, vm.Name)
+		}
+		seen[vm.Name] = true
+
+		if vm.URL == "" && vm.InstanceID == "" {
+			return fmt.Errorf("vm %q: either url or instance_id is required", vm.Name)
+		}
+		if vm.UsesComputeAPI() && c.ServiceAccountKey == "" {
+			return fmt.Errorf("vm %q: instance_id requires YC_SA_KEY_FILE", vm.Name)
+		}
+		if err := c.VMs[i].Policy.normalize(); err != nil {
+			return fmt.Errorf("vm %q: policy: %w", vm.Name, err)
+		}
+		for j, w := range vm.Maintenance {
+			if _, err := w.Window(); err != nil {
+				return fmt.Errorf("vm %q: maintenance[%d]: %w", vm.Name, j, err)
+			}
+		}
+		if vm.DesiredState != "" {
+			desired, ok := types.ParseDesiredState(string(vm.DesiredState))
+			if !ok {
+				return fmt.Errorf("vm %q: desired_state must be running, stopped or unmanaged, got %q", vm.Name, vm.DesiredState)
+			}
+			c.VMs[i].DesiredState = desired
+		}
+		if vm.Schedule != nil {
+			if c.VMs[i].Desired() != types.DesiredRunning {
+				return fmt.Errorf("vm %q: schedule requires desired_state running", vm.Name)
+			}
+			if _, err := vm.Schedule.Hours(); err != nil {
+				return fmt.Errorf("vm %q: schedule: %w", vm.Name, err)
+			}
+		}
+		probeNames := make(map[string]bool, len(vm.Probes))
+		for j, p := range vm.Probes {
+			built, err := p.Probe()
+			if err != nil {
+				return fmt.Errorf("vm %q: probes[%d]: %w", vm.Name, j, err)
+			}
+			if probeNames[built.Name()] {
+				return fmt.Errorf("vm %q: probes[%d]: duplicate name %q", vm.Name, j, built.Name())
+			}
+			probeNames[built.Name()] = true
+		}
+		required, err := probe.ParseRequirement(vm.ProbesRequired)
+		if err != nil {
+			return fmt.Errorf("vm %q: probes_required: %w", vm.Name, err)
+		}
+		if int(required) > len(vm.Probes) {
+			return fmt.Errorf("vm %q: probes_required is %d, but only %d probes are configured", vm.Name, required, len(vm.Probes))
+		}
+	}
+	return nil
+}
+
+// SaveVMs writes the current VM list back to the YAML file.
+// Other sections and comments in the file are preserved.
 func (c *Config) SaveVMs(path string) error {
-	data := struct {
-		VMs []VM `yaml:"vms"`
-	}{
-		VMs: c.VMs,
+	var doc yaml.Node
+	if data, err := os.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse YAML: %w", err)
+		}
+	}
+
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
 	}
 
-	out, err := yaml.Marshal(data)
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to update YAML: top level is not a mapping")
+	}
+
+	var vmsNode yaml.Node
+	if err := vmsNode.Encode(c.VMs); err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	setMappingValue(root, "vms", &vmsNode)
+
+	out, err := yaml.Marshal(&doc)
 	if err != nil {
 		return fmt.Errorf("failed to marshal YAML: %w", err)
 	}
@@ -113,6 +475,21 @@ func (c *Config) SaveVMs(path string) error {
 	return nil
 }
 
+// setMappingValue replaces the value of key in a mapping node, appending the key if missing
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
 func getEnvInt(key string, defaultVal int) int {
 	if val := os.Getenv(key); val != "" {
 		if i, err := strconv.Atoi(val); err == nil {
@@ -122,6 +499,22 @@ func getEnvInt(key string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvString(key string, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
+
+func getEnvBool(key string, defaultVal bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
 func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
 	if val := os.Getenv(key); val != "" {
 		if d, err := time.ParseDuration(val); err == nil {
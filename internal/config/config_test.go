@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestSaveVMs_PreservesOtherSections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vms.yaml")
+	original := `# watchdog settings
+notifiers:
+  slack:
+    enabled: true
+    webhook_url: https://hooks.example.com/x
+vms:
+  - name: web
+    url: https://gw.example.com/web
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{}
+	if err := cfg.loadYAML(path); err != nil {
+		t.Fatalf("loadYAML: %v", err)
+	}
+	cfg.VMs[0].IP = "10.0.0.5"
+
+	if err := cfg.SaveVMs(path); err != nil {
+		t.Fatalf("SaveVMs: %v", err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# watchdog settings", "webhook_url: https://hooks.example.com/x", "ip: 10.0.0.5"} {
+		if !strings.Contains(string(saved), want) {
+			t.Errorf("saved file lost %q:\n%s", want, saved)
+		}
+	}
+
+	reloaded := &Config{}
+	if err := reloaded.loadYAML(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reloaded.Notifiers.Slack.Enabled || len(reloaded.VMs) != 1 {
+		t.Errorf("unexpected reloaded config: %+v", reloaded)
+	}
+}
+
+func TestSaveVMs_CreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vms.yaml")
+
+	cfg := &Config{VMs: []VM{{Name: "web", URL: "https://gw"}}}
+	if err := cfg.SaveVMs(path); err != nil {
+		t.Fatalf("SaveVMs: %v", err)
+	}
+
+	reloaded := &Config{}
+	if err := reloaded.loadYAML(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(reloaded.VMs) != 1 || reloaded.VMs[0].Name != "web" {
+		t.Errorf("unexpected VMs: %+v", reloaded.VMs)
+	}
+}
+
+func TestValidateVMs_DesiredState(t *testing.T) {
+	tests := []struct {
+		name string
+		vm   VM
+		want string
+	}{
+		{"default", VM{Name: "a", URL: "u"}, ""},
+		{"case-insensitive", VM{Name: "a", URL: "u", DesiredState: "Unmanaged"}, ""},
+		{"unknown", VM{Name: "a", URL: "u", DesiredState: "paused"}, "desired_state"},
+		{"schedule on stopped VM", VM{Name: "a", URL: "u", DesiredState: "stopped",
+			Schedule: &PowerSchedule{From: "08:00", To: "20:00"}}, "schedule requires"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{VMs: []VM{tt.vm}}
+			err := cfg.validateVMs()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tt.vm.DesiredState != "" && cfg.VMs[0].DesiredState != types.DesiredUnmanaged {
+					t.Errorf("expected desired state to be normalized, got %q", cfg.VMs[0].DesiredState)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateVMs_Probes(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes []Probe
+		want   string
+	}{
+		{"tcp and http", []Probe{{TCP: "{ip}:22"}, {HTTP: "http://{ip}/health", ExpectStatus: 204}}, ""},
+		{"neither", []Probe{{Name: "empty"}}, "either tcp or http"},
+		{"both", []Probe{{TCP: "{ip}:80", HTTP: "http://{ip}"}}, "only one"},
+		{"bad url", []Probe{{HTTP: "{ip}/health"}}, "http://"},
+		{"duplicate", []Probe{{Name: "app", TCP: "{ip}:80"}, {Name: "app", TCP: "{ip}:443"}}, "duplicate"},
+		{"threshold", []Probe{{TCP: "{ip}:22", FailureThreshold: -2}}, "failure_threshold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{VMs: []VM{{Name: "a", URL: "u", Probes: tt.probes}}}
+			err := cfg.validateVMs()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateVMs_ProbesRequired(t *testing.T) {
+	probes := []Probe{{TCP: "{ip}:22"}, {TCP: "{ip}:80"}}
+	tests := []struct {
+		required string
+		want     string
+	}{
+		{"", ""},
+		{"any", ""},
+		{"2", ""},
+		{"3", "only 2 probes"},
+		{"most", "probes_required"},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{VMs: []VM{{Name: "a", URL: "u", Probes: probes, ProbesRequired: tt.required}}}
+		err := cfg.validateVMs()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error: %v", tt.required, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.required, tt.want, err)
+		}
+	}
+}
+
+func TestValidateEscalationChannels(t *testing.T) {
+	step := EscalationStep{After: time.Minute, Channels: []string{"email"}}
+	cfg := &Config{
+		VMs: []VM{{Name: "db", URL: "u", Policy: Policy{Escalation: []EscalationStep{step}}}},
+	}
+	if err := cfg.validateEscalationChannels(); err == nil || !strings.Contains(err.Error(), `vm "db"`) {
+		t.Errorf("expected a disabled channel to be rejected, got %v", err)
+	}
+
+	cfg.Notifiers.Email.Enabled = true
+	if err := cfg.validateEscalationChannels(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
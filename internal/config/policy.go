@@ -0,0 +1,357 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+// Policy overrides how often a VM is checked and how long it may stay in a
+// transitional status. Unset values fall back to the global policy and then
+// to the built-in defaults of types.VMStatus.
+type Policy struct {
+	// GracePeriod skips checks after a start request while the VM boots
+	GracePeriod time.Duration `yaml:"grace_period,omitempty"`
+	// Intervals sets the check interval per status
+	Intervals map[types.VMStatus]time.Duration `yaml:"intervals,omitempty"`
+	// Timeouts sets when a transitional status is reported as stuck
+	Timeouts map[types.VMStatus]time.Duration `yaml:"timeouts,omitempty"`
+	// RestartBudget limits automatic starts of a crash-looping VM
+	RestartBudget RestartBudget `yaml:"restart_budget,omitempty"`
+	// PingFailures is how many checks in a row must fail to ping a running VM
+	// before the API is asked; higher values ride out a flaky network
+	PingFailures int `yaml:"ping_failures,omitempty"`
+	// Remediation restarts a running VM whose probes keep failing
+	Remediation Remediation `yaml:"remediation,omitempty"`
+	// StuckActions sets what to do once a VM is stuck in a transitional status
+	StuckActions map[types.VMStatus]StuckAction `yaml:"stuck_actions,omitempty"`
+	// Flapping collapses alerts of a VM that keeps going up and down
+	Flapping Flapping `yaml:"flapping,omitempty"`
+	// Escalation re-notifies about incidents nobody acknowledges
+	Escalation []EscalationStep `yaml:"escalation,omitempty"`
+}
+
+// EscalationStep re-notifies about an incident that is still unacknowledged
+// and unresolved After its opening, mentioning Mention (Telegram @usernames or
+// numeric user IDs) and additionally delivering to the named Channels
+type EscalationStep struct {
+	After    time.Duration `yaml:"after"`
+	Mention  []string      `yaml:"mention,omitempty"`
+	Channels []string      `yaml:"channels,omitempty"`
+}
+
+// NotifierNames lists the notification backends an escalation step can name
+var NotifierNames = []string{"telegram", "webhook", "slack", "email"}
+
+// Flapping configures flap detection: the weighted percentage of state changes
+// over the last Samples checks starts flapping above High and ends it below Low
+type Flapping struct {
+	Disabled bool    `yaml:"disabled,omitempty"`
+	Samples  int     `yaml:"samples,omitempty"`
+	High     float64 `yaml:"high,omitempty"`
+	Low      float64 `yaml:"low,omitempty"`
+}
+
+// StuckAction is what to do with a VM stuck in a transitional status
+type StuckAction string
+
+const (
+	// StuckAlert only alerts (default)
+	StuckAlert StuckAction = "alert"
+	// StuckStart requests a start again
+	StuckStart StuckAction = "start"
+	// StuckStop requests a stop again
+	StuckStop StuckAction = "stop"
+	// StuckRestart restarts the VM
+	StuckRestart StuckAction = "restart"
+	// StuckStopStart stops the VM and starts it once it is stopped
+	StuckStopStart StuckAction = "stop_start"
+)
+
+// validStuckActions lists the actions accepted in stuck_actions
+var validStuckActions = []StuckAction{StuckAlert, StuckStart, StuckStop, StuckRestart, StuckStopStart}
+
+// Remediation is the ladder for a VM that runs while its application is down:
+// alert, wait RestartAfter, restart the VM, and alert again if the application
+// has not recovered VerifyAfter later. RestartAfter 0 only alerts.
+type Remediation struct {
+	RestartAfter time.Duration `yaml:"restart_after,omitempty"`
+	VerifyAfter  time.Duration `yaml:"verify_after,omitempty"`
+}
+
+// RestartBudget allows at most MaxStarts automatic starts per sliding Window
+type RestartBudget struct {
+	MaxStarts int           `yaml:"max_starts,omitempty"`
+	Window    time.Duration `yaml:"window,omitempty"`
+}
+
+// Merge returns p with the values set in override taking precedence
+func (p Policy) Merge(override Policy) Policy {
+	merged := Policy{
+		GracePeriod:  p.GracePeriod,
+		Intervals:    mergeByStatus(p.Intervals, override.Intervals),
+		Timeouts:     mergeByStatus(p.Timeouts, override.Timeouts),
+		StuckActions: mergeByStatus(p.StuckActions, override.StuckActions),
+	}
+	if override.GracePeriod > 0 {
+		merged.GracePeriod = override.GracePeriod
+	}
+	merged.RestartBudget = p.RestartBudget
+	if override.RestartBudget.MaxStarts > 0 {
+		merged.RestartBudget.MaxStarts = override.RestartBudget.MaxStarts
+	}
+	if override.RestartBudget.Window > 0 {
+		merged.RestartBudget.Window = override.RestartBudget.Window
+	}
+	merged.PingFailures = p.PingFailures
+	if override.PingFailures > 0 {
+		merged.PingFailures = override.PingFailures
+	}
+	merged.Flapping = p.Flapping
+	if override.Flapping.Disabled {
+		merged.Flapping.Disabled = true
+	}
+	if override.Flapping.Samples > 0 {
+		merged.Flapping.Samples = override.Flapping.Samples
+	}
+	if override.Flapping.High > 0 {
+		merged.Flapping.High = override.Flapping.High
+	}
+	if override.Flapping.Low > 0 {
+		merged.Flapping.Low = override.Flapping.Low
+	}
+	merged.Escalation = p.Escalation
+	if len(override.Escalation) > 0 {
+		// Steps build on each other: a VM's own ladder replaces the global one
+		merged.Escalation = override.Escalation
+	}
+	merged.Remediation = p.Remediation
+	if override.Remediation.RestartAfter > 0 {
+		merged.Remediation.RestartAfter = override.Remediation.RestartAfter
+	}
+	if override.Remediation.VerifyAfter > 0 {
+		merged.Remediation.VerifyAfter = override.Remediation.VerifyAfter
+	}
+	return merged
+}
+
+// CheckInterval returns the check interval for a VM in status s
+func (p Policy) CheckInterval(s types.VMStatus, min, max time.Duration) time.Duration {
+	if d, ok := p.Intervals[s]; ok {
+		return d
+	}
+	return s.GetCheckInterval(min, max)
+}
+
+// Timeout returns how long a VM may stay in status s before it is stuck (0: never)
+func (p Policy) Timeout(s types.VMStatus) time.Duration {
+	if d, ok := p.Timeouts[s]; ok {
+		return d
+	}
+	return s.GetTimeout()
+}
+
+// Grace returns the grace period after a start request
+func (p Policy) Grace() time.Duration {
+	if p.GracePeriod > 0 {
+		return p.GracePeriod
+	}
+	return types.DefaultGracePeriod
+}
+
+// Budget returns how many automatic starts are allowed within which window
+func (p Policy) Budget() (maxStarts int, window time.Duration) {
+	maxStarts, window = p.RestartBudget.MaxStarts, p.RestartBudget.Window
+	if maxStarts <= 0 {
+		maxStarts = types.DefaultMaxAutoStarts
+	}
+	if window <= 0 {
+		window = types.DefaultRestartWindow
+	}
+	return maxStarts, window
+}
+
+// StuckAction returns what to do once a VM is stuck in status s
+func (p Policy) StuckAction(s types.VMStatus) StuckAction {
+	if a, ok := p.StuckActions[s]; ok {
+		return a
+	}
+	return StuckAlert
+}
+
+// FlapThresholds returns how many checks flap detection looks at and the
+// percentages that start and end flapping; samples is 0 when it is disabled
+func (p Policy) FlapThresholds() (samples int, high, low float64) {
+	if p.Flapping.Disabled {
+		return 0, 0, 0
+	}
+	samples, high, low = p.Flapping.Samples, p.Flapping.High, p.Flapping.Low
+	if samples <= 0 {
+		samples = types.DefaultFlapSamples
+	}
+	if high <= 0 {
+		high = types.DefaultFlapHigh
+	}
+	if low <= 0 {
+		low = types.DefaultFlapLow
+	}
+	return samples, high, low
+}
+
+// PingThreshold returns how many failed pings in a row lead to an API check
+func (p Policy) PingThreshold() int {
+	if p.PingFailures > 0 {
+		return p.PingFailures
+	}
+	return types.DefaultPingFailureThreshold
+}
+
+// RemediationSteps returns when to restart a VM whose application is down
+// (0: never) and how long to wait for the restart to help
+func (p Policy) RemediationSteps() (restartAfter, verifyAfter time.Duration) {
+	restartAfter, verifyAfter = p.Remediation.RestartAfter, p.Remediation.VerifyAfter
+	if verifyAfter <= 0 {
+		verifyAfter = types.DefaultRestartVerifyAfter
+	}
+	return restartAfter, verifyAfter
+}
+
+// normalize validates the policy and rewrites status keys to their canonical spelling
+func (p *Policy) normalize() error {
+	if p.GracePeriod < 0 {
+		return fmt.Errorf("grace_period must not be negative")
+	}
+	if p.RestartBudget.MaxStarts < 0 || p.RestartBudget.Window < 0 {
+		return fmt.Errorf("restart_budget: max_starts and window must not be negative")
+	}
+	if p.PingFailures < 0 {
+		return fmt.Errorf("ping_failures must not be negative")
+	}
+	if f := p.Flapping; f.Samples < 0 || f.Samples == 1 || f.Samples == 2 {
+		return fmt.Errorf("flapping: samples must be at least 3")
+	}
+	if f := p.Flapping; f.High < 0 || f.High > 100 || f.Low < 0 || f.Low > 100 {
+		return fmt.Errorf("flapping: high and low are percentages from 0 to 100")
+	}
+	if f := p.Flapping; f.High > 0 && f.Low > 0 && f.Low >= f.High {
+		return fmt.Errorf("flapping: low must be below high")
+	}
+	if p.Remediation.RestartAfter < 0 || p.Remediation.VerifyAfter < 0 {
+		return fmt.Errorf("remediation: restart_after and verify_after must not be negative")
+	}
+
+	if err := validateEscalation(p.Escalation); err != nil {
+		return err
+	}
+
+	var err error
+	if p.Intervals, err = normalizeDurations("intervals", p.Intervals); err != nil {
+		return err
+	}
+	if p.Timeouts, err = normalizeDurations("timeouts", p.Timeouts); err != nil {
+		return err
+	}
+
+	for s := range p.Timeouts {
+		if !s.IsTransitional() {
+			return fmt.Errorf("timeouts.%s: only transitional statuses can get stuck", s)
+		}
+	}
+
+	if len(p.StuckActions) > 0 {
+		actions := make(map[types.VMStatus]StuckAction, len(p.StuckActions))
+		for key, action := range p.StuckActions {
+			s, ok := types.ParseVMStatus(string(key))
+			if !ok {
+				return fmt.Errorf("stuck_actions: unknown status %q", key)
+			}
+			if !s.IsTransitional() {
+				return fmt.Errorf("stuck_actions.%s: only transitional statuses can get stuck", s)
+			}
+			action = StuckAction(strings.ToLower(string(action)))
+			if !slices.Contains(validStuckActions, action) {
+				return fmt.Errorf("stuck_actions.%s: unknown action %q", s, action)
+			}
+			actions[s] = action
+		}
+		p.StuckActions = actions
+	}
+	return nil
+}
+
+func validateEscalation(steps []EscalationStep) error {
+	var prev time.Duration
+	for i, step := range steps {
+		if step.After <= prev {
+			return fmt.Errorf("escalation[%d]: after must be positive and later than the previous step", i)
+		}
+		prev = step.After
+		for _, mention := range step.Mention {
+			if !validMention(mention) {
+				return fmt.Errorf("escalation[%d]: mention %q must be a @username or a numeric user ID", i, mention)
+			}
+		}
+		for _, name := range step.Channels {
+			if !slices.Contains(NotifierNames, name) {
+				return fmt.Errorf("escalation[%d]: unknown channel %q (valid: %s)", i, name, strings.Join(NotifierNames, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// validMention accepts a Telegram @username or a numeric user ID
+func validMention(s string) bool {
+	if name, ok := strings.CutPrefix(s, "@"); ok {
+		return name != "" && !strings.ContainsAny(name, " @")
+	}
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeDurations(field string, in map[types.VMStatus]time.Duration) (map[types.VMStatus]time.Duration, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	out := make(map[types.VMStatus]time.Duration, len(in))
+	for key, d := range in {
+		s, ok := types.ParseVMStatus(string(key))
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown status %q", field, key)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%s.%s: duration must be positive", field, s)
+		}
+		out[s] = d
+	}
+	return out, nil
+}
+
+func mergeByStatus[V any](base, override map[types.VMStatus]V) map[types.VMStatus]V {
+	if len(override) == 0 {
+		return base
+	}
+	if len(base) == 0 {
+		return override
+	}
+
+	merged := make(map[types.VMStatus]V, len(base)+len(override))
+	for s, d := range base {
+		merged[s] = d
+	}
+	for s, d := range override {
+		merged[s] = d
+	}
+	return merged
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestPolicy_FromYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vms.yaml")
+	data := `policy:
+  grace_period: 90s
+  ping_failures: 3
+  remediation:
+    restart_after: 10m
+  intervals:
+    stopped: 10s
+  timeouts:
+    Starting: 8m
+  stuck_actions:
+    stopping: Stop_Start
+vms:
+  - name: db
+    url: https://gw/db
+    policy:
+      grace_period: 12m
+      remediation:
+        verify_after: 15m
+      stuck_actions:
+        Starting: restart
+      timeouts:
+        starting: 15m
+  - name: worker
+    url: https://gw/worker
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{}
+	if err := cfg.loadYAML(path); err != nil {
+		t.Fatalf("loadYAML: %v", err)
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	db := cfg.Policy.Merge(cfg.VMs[0].Policy)
+	if db.Grace() != 12*time.Minute {
+		t.Errorf("db grace period = %v, want 12m", db.Grace())
+	}
+	if got := db.Timeout(types.StatusStarting); got != 15*time.Minute {
+		t.Errorf("db starting timeout = %v, want 15m", got)
+	}
+	if got := db.CheckInterval(types.StatusStopped, time.Second, time.Minute); got != 10*time.Second {
+		t.Errorf("db stopped interval = %v, want global 10s", got)
+	}
+
+	if db.PingThreshold() != 3 {
+		t.Errorf("db ping threshold = %d, want global 3", db.PingThreshold())
+	}
+	if restart, verify := db.RemediationSteps(); restart != 10*time.Minute || verify != 15*time.Minute {
+		t.Errorf("db remediation = %v/%v, want global 10m and own 15m", restart, verify)
+	}
+
+	worker := cfg.Policy.Merge(cfg.VMs[1].Policy)
+	if worker.Grace() != 90*time.Second || worker.Timeout(types.StatusStarting) != 8*time.Minute {
+		t.Errorf("worker should use the global policy, got %+v", worker)
+	}
+	if got := worker.Timeout(types.StatusStopping); got != types.StatusStopping.GetTimeout() {
+		t.Errorf("unset timeout = %v, want built-in default", got)
+	}
+	if db.StuckAction(types.StatusStarting) != StuckRestart || db.StuckAction(types.StatusStopping) != StuckStopStart {
+		t.Errorf("db stuck actions = %v, want own restart and global stop_start", db.StuckActions)
+	}
+	if worker.StuckAction(types.StatusStarting) != StuckAlert {
+		t.Errorf("unset stuck action = %q, want alert", worker.StuckAction(types.StatusStarting))
+	}
+	if samples, high, low := worker.FlapThresholds(); samples != types.DefaultFlapSamples || high != types.DefaultFlapHigh || low != types.DefaultFlapLow {
+		t.Errorf("unset flapping = %d/%v/%v, want built-in defaults", samples, high, low)
+	}
+	if samples, _, _ := cfg.Policy.Merge(Policy{Flapping: Flapping{Disabled: true}}).FlapThresholds(); samples != 0 {
+		t.Errorf("expected disabled flap detection to report no samples, got %d", samples)
+	}
+	if _, verify := worker.RemediationSteps(); verify != types.DefaultRestartVerifyAfter {
+		t.Errorf("unset verify_after = %v, want built-in default", verify)
+	}
+	if got := worker.CheckInterval(types.StatusRunning, time.Second, time.Minute); got != time.Minute {
+		t.Errorf("unset interval = %v, want built-in default", got)
+	}
+}
+
+func TestPolicy_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   string
+	}{
+		{"unknown status", Policy{Intervals: map[types.VMStatus]time.Duration{"Sleeping": time.Second}}, "unknown status"},
+		{"non-positive", Policy{Intervals: map[types.VMStatus]time.Duration{"Running": 0}}, "must be positive"},
+		{"unknown stuck action", Policy{StuckActions: map[types.VMStatus]StuckAction{"Starting": "reboot"}}, "unknown action"},
+		{"stable stuck action", Policy{StuckActions: map[types.VMStatus]StuckAction{"Stopped": "start"}}, "transitional"},
+		{"too few flap samples", Policy{Flapping: Flapping{Samples: 2}}, "at least 3"},
+		{"flap thresholds", Policy{Flapping: Flapping{High: 20, Low: 30}}, "low must be below high"},
+		{"negative ping failures", Policy{PingFailures: -1}, "ping_failures"},
+		{"escalation order", Policy{Escalation: []EscalationStep{{After: time.Hour}, {After: time.Minute}}}, "later than the previous step"},
+		{"escalation mention", Policy{Escalation: []EscalationStep{{After: time.Minute, Mention: []string{"alice"}}}}, "@username"},
+		{"escalation channel", Policy{Escalation: []EscalationStep{{After: time.Minute, Channels: []string{"pager"}}}}, "unknown channel"},
+		{"stable timeout", Policy{Timeouts: map[types.VMStatus]time.Duration{"Running": time.Minute}}, "transitional"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.normalize()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSaveVMs_KeepsPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vms.yaml")
+
+	cfg := &Config{VMs: []VM{
+		{Name: "db", URL: "https://gw", Policy: Policy{GracePeriod: 12 * time.Minute}},
+		{Name: "web", URL: "https://gw"},
+	}}
+	if err := cfg.SaveVMs(path); err != nil {
+		t.Fatalf("SaveVMs: %v", err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(saved), "policy:") != 1 || !strings.Contains(string(saved), "grace_period: 12m0s") {
+		t.Errorf("unexpected saved policy:\n%s", saved)
+	}
+}
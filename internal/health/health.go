@@ -0,0 +1,137 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Status values reported for components and the service as a whole
+const (
+	StatusOK        = "ok"
+	StatusUnhealthy = "unhealthy"
+)
+
+// Check reports a component's problem, or nil when it is healthy
+type Check func() error
+
+// ComponentReport is the result of a single check
+type ComponentReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the JSON body served by the health endpoints
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentReport `json:"components"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker aggregates component checks into liveness and readiness reports.
+// Readiness includes every liveness check.
+type Checker struct {
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+// NewChecker creates a checker without components
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// AddLiveness registers a check that fails when the process needs a restart
+func (c *Checker) AddLiveness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, namedCheck{name, check})
+}
+
+// AddReadiness registers a check that fails while the service is not fully working yet
+func (c *Checker) AddReadiness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness = append(c.readiness, namedCheck{name, check})
+}
+
+// Liveness runs the liveness checks
+func (c *Checker) Liveness() Report {
+	c.mu.RLock()
+	checks := append([]namedCheck{}, c.liveness...)
+	c.mu.RUnlock()
+	return run(checks)
+}
+
+// Readiness runs the liveness and readiness checks
+func (c *Checker) Readiness() Report {
+	c.mu.RLock()
+	checks := append(append([]namedCheck{}, c.liveness...), c.readiness...)
+	c.mu.RUnlock()
+	return run(checks)
+}
+
+// LivenessHandler serves the liveness report (503 when unhealthy)
+func (c *Checker) LivenessHandler() http.Handler {
+	return reportHandler(c.Liveness)
+}
+
+// ReadinessHandler serves the readiness report (503 when not ready)
+func (c *Checker) ReadinessHandler() http.Handler {
+	return reportHandler(c.Readiness)
+}
+
+func run(checks []namedCheck) Report {
+	report := Report{
+		Status:     StatusOK,
+		Components: make(map[string]ComponentReport, len(checks)),
+	}
+
+	for _, nc := range checks {
+		if err := nc.check(); err != nil {
+			report.Status = StatusUnhealthy
+			report.Components[nc.name] = ComponentReport{Status: StatusUnhealthy, Error: err.Error()}
+			continue
+		}
+		report.Components[nc.name] = ComponentReport{Status: StatusOK}
+	}
+
+	return report
+}
+
+func reportHandler(report func() Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep := report()
+
+		w.Header().Set("Content-Type", "application/json")
+		if rep.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(rep)
+	})
+}
+
+// State is a component whose health is set from outside, e.g. the result
+// of the last configuration load
+type State struct {
+	mu  sync.RWMutex
+	err error
+}
+
+// Set records the component's current problem, or nil once it is healthy again
+func (s *State) Set(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Check implements Check
+func (s *State) Check() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(t *testing.T, h http.Handler) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	var rep Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &rep); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, rep
+}
+
+func TestChecker_Healthy(t *testing.T) {
+	c := NewChecker()
+	c.AddLiveness("monitors", func() error { return nil })
+
+	code, rep := serve(t, c.LivenessHandler())
+	if code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+	if rep.Status != StatusOK || rep.Components["monitors"].Status != StatusOK {
+		t.Errorf("unexpected report: %+v", rep)
+	}
+}
+
+func TestChecker_ReportsFailingComponent(t *testing.T) {
+	c := NewChecker()
+	config := &State{}
+	c.AddLiveness("monitors", func() error { return nil })
+	c.AddLiveness("config", config.Check)
+	c.AddReadiness("startup", func() error { return errors.New("waiting for first check: vm-1") })
+
+	code, rep := serve(t, c.LivenessHandler())
+	if code != http.StatusOK {
+		t.Errorf("liveness must not include readiness checks, got %d: %+v", code, rep)
+	}
+
+	config.Set(errors.New("vms.yaml: invalid"))
+
+	code, rep = serve(t, c.LivenessHandler())
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", code)
+	}
+	if got := rep.Components["config"]; got.Status != StatusUnhealthy || got.Error != "vms.yaml: invalid" {
+		t.Errorf("unexpected config component: %+v", got)
+	}
+	if rep.Components["monitors"].Status != StatusOK {
+		t.Errorf("expected monitors to stay healthy: %+v", rep)
+	}
+
+	config.Set(nil)
+	code, rep = serve(t, c.ReadinessHandler())
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness 503, got %d", code)
+	}
+	if len(rep.Components) != 3 || rep.Components["startup"].Status != StatusUnhealthy {
+		t.Errorf("unexpected readiness report: %+v", rep)
+	}
+}
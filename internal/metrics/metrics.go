@@ -0,0 +1,104 @@
+package metrics
+
+import "time"
+
+// Outcome label values
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	// OutcomeExpired is a notification given up on after retrying it for too long
+	OutcomeExpired = "expired"
+	// OutcomeRejected is a notification the destination refused for good
+	OutcomeRejected = "rejected"
+)
+
+var (
+	// VMStatus is 1 for the VM's current status and absent for all others
+	VMStatus = NewGaugeVec("watchdog_vm_status",
+		"Current VM status as seen by the watchdog (1 for the active status)", "vm", "status")
+
+	// VMStatusDuration is refreshed on every scrape
+	VMStatusDuration = NewGaugeVec("watchdog_vm_status_duration_seconds",
+		"Seconds the VM has been in its current status", "vm")
+
+	VMPingRTT = NewGaugeVec("watchdog_vm_ping_rtt_seconds",
+		"Average round-trip time of the last successful ping", "vm")
+
+	VMPingLoss = NewGaugeVec("watchdog_vm_ping_loss_ratio",
+		"Fraction of echo requests without a reply in the last ping", "vm")
+
+	VMProbeLatency = NewGaugeVec("watchdog_vm_probe_latency_seconds",
+		"Duration of the last run of a TCP or HTTP probe", "vm", "probe")
+
+	VMProbeHealthy = NewGaugeVec("watchdog_vm_probe_healthy",
+		"1 while all probes of the VM pass, 0 while any fails", "vm")
+
+	VMCrashLooping = NewGaugeVec("watchdog_vm_crash_looping",
+		"1 while automatic starts of the VM are suspended after exhausting its restart budget", "vm")
+
+	VMFlapping = NewGaugeVec("watchdog_vm_flapping",
+		"1 while the VM is flapping and its failure and recovery alerts are suppressed", "vm")
+
+	Pings = NewCounterVec("watchdog_pings_total",
+		"Ping checks by result", "vm", "result")
+
+	Probes = NewCounterVec("watchdog_probes_total",
+		"TCP and HTTP probe runs by result", "vm", "probe", "result")
+
+	APICalls = NewCounterVec("watchdog_api_calls_total",
+		"Yandex Cloud API calls by backend, endpoint and outcome", "backend", "endpoint", "outcome")
+
+	RateLimiterWait = NewCounterVec("watchdog_rate_limiter_wait_seconds_total",
+		"Time spent waiting for the API rate limiter", "backend")
+
+	StartAttempts = NewCounterVec("watchdog_vm_start_attempts_total",
+		"VM start requests by result", "vm", "result")
+	StopAttempts = NewCounterVec("watchdog_vm_stop_attempts_total",
+		"VM stop requests by result", "vm", "result")
+	RestartAttempts = NewCounterVec("watchdog_vm_restart_attempts_total",
+		"VM restart requests by result", "vm", "result")
+
+	NotificationsEnqueued = NewCounterVec("watchdog_notifications_enqueued_total",
+		"Notifications passed to the queue by outcome (queued, deduplicated, dropped, muted, filtered)", "outcome")
+
+	NotificationsDropped = NewCounterVec("watchdog_notifications_dropped_total",
+		"Notifications a channel lost because its outbox was full", "channel")
+
+	NotificationsSent = NewCounterVec("watchdog_notifications_sent_total",
+		"Notification delivery attempts by channel and outcome (success, error, expired, rejected)", "channel", "outcome")
+
+	NotificationsPending = NewGaugeVec("watchdog_notifications_pending",
+		"Notifications in the outbox waiting for delivery or a retry", "channel")
+)
+
+// SetVMStatus records the VM's current status
+func SetVMStatus(vm, status string) {
+	VMStatus.DeleteWhere("vm", vm)
+	VMStatus.Set(1, vm, status)
+}
+
+// ForgetVM removes per-VM gauges when a VM is no longer monitored
+func ForgetVM(vm string) {
+	VMStatus.DeleteWhere("vm", vm)
+	VMStatusDuration.DeleteWhere("vm", vm)
+	VMPingRTT.DeleteWhere("vm", vm)
+	VMPingLoss.DeleteWhere("vm", vm)
+	VMProbeLatency.DeleteWhere("vm", vm)
+	VMProbeHealthy.DeleteWhere("vm", vm)
+	VMCrashLooping.DeleteWhere("vm", vm)
+	VMFlapping.DeleteWhere("vm", vm)
+}
+
+// ObserveAPICall counts an API call
+func ObserveAPICall(backend, endpoint string, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	APICalls.Inc(backend, endpoint, outcome)
+}
+
+// ObserveRateLimiterWait adds time spent blocked on a rate limiter
+func ObserveRateLimiterWait(backend string, d time.Duration) {
+	RateLimiterWait.Add(d.Seconds(), backend)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func render(r *Registry) string {
+	var sb strings.Builder
+	w := bufio.NewWriter(&sb)
+	r.WriteTo(w)
+	w.Flush()
+	return sb.String()
+}
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	calls := &CounterVec{v: newVec(r, "counter", "test_calls_total", "Calls", []string{"endpoint", "outcome"})}
+	temp := &GaugeVec{v: newVec(r, "gauge", "test_temperature", "Temperature", []string{"room"})}
+
+	calls.Inc("start", OutcomeSuccess)
+	calls.Add(2, "start", OutcomeSuccess)
+	calls.Add(-1, "start", OutcomeSuccess) // ignored, counters never decrease
+	calls.Inc("info", OutcomeError)
+	temp.Set(21.5, `kitchen "A"`)
+
+	want := `# HELP test_calls_total Calls
+# TYPE test_calls_total counter
+test_calls_total{endpoint="info",outcome="error"} 1
+test_calls_total{endpoint="start",outcome="success"} 3
+# HELP test_temperature Temperature
+# TYPE test_temperature gauge
+test_temperature{room="kitchen \"A\""} 21.5
+`
+	if got := render(r); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistry_OnScrapeAndDelete(t *testing.T) {
+	r := NewRegistry()
+	status := &GaugeVec{v: newVec(r, "gauge", "test_status", "Status", []string{"vm", "status"})}
+
+	scrapes := 0
+	r.OnScrape(func() { scrapes++ })
+
+	status.Set(1, "vm-1", "Running")
+	status.Set(1, "vm-2", "Stopped")
+	status.DeleteWhere("vm", "vm-1")
+
+	out := render(r)
+	if scrapes != 1 {
+		t.Fatalf("expected scrape hook to run once, ran %d times", scrapes)
+	}
+	if strings.Contains(out, `vm="vm-1"`) {
+		t.Errorf("expected vm-1 series to be deleted, got:\n%s", out)
+	}
+	if !strings.Contains(out, `test_status{vm="vm-2",status="Stopped"} 1`) {
+		t.Errorf("expected vm-2 series, got:\n%s", out)
+	}
+}
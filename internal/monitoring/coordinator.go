@@ -2,42 +2,95 @@ package monitoring
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fxfuren/yandex-watcher-bot/internal/client"
 	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/health"
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
 	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/state"
 	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
 )
 
+// ErrUnknownVM is returned when an operation names a VM that is not monitored
+var ErrUnknownVM = errors.New("unknown VM")
+
+// ErrNotPaused is returned when resuming a VM that is not paused
+var ErrNotPaused = errors.New("VM is not paused")
+
+const (
+	// heartbeatMultiple is how many check intervals a monitor loop may miss
+	// before it is reported as stalled
+	heartbeatMultiple = 3
+	// heartbeatSlack covers a single slow check (ping attempts, API retries)
+	heartbeatSlack = time.Minute
+)
+
 // Coordinator manages all VM monitors
 type Coordinator struct {
 	config       *config.Config
-	client       *client.YandexClient
+	gateway      *client.YandexClient
+	compute      *client.ComputeClient
 	notifier     *notification.NotificationQueue
+	store        *state.Store
+	configHealth *health.State
+	configHash   string // content of vms.yaml as last loaded or saved
 	monitors     []*VMMonitor
+	cancels      map[string]context.CancelFunc
+	discovered   map[string]string // instance ID -> VM name of discovered monitors
+	monitorsMu   sync.RWMutex
 	configMu     sync.Mutex
 	ipUpdateChan chan string
+	reloadChan   chan struct{}
 	wg           sync.WaitGroup
 }
 
-// NewCoordinator creates a new coordinator
-func NewCoordinator(cfg *config.Config, client *client.YandexClient, notifier *notification.NotificationQueue) *Coordinator {
-	return &Coordinator{
+// NewCoordinator creates a new coordinator.
+// compute may be nil when no VM is configured by instance ID.
+func NewCoordinator(cfg *config.Config, gateway *client.YandexClient, compute *client.ComputeClient, notifier *notification.NotificationQueue) *Coordinator {
+	c := &Coordinator{
 		config:       cfg,
-		client:       client,
+		gateway:      gateway,
+		compute:      compute,
 		notifier:     notifier,
 		monitors:     make([]*VMMonitor, 0, len(cfg.VMs)),
+		cancels:      make(map[string]context.CancelFunc),
+		discovered:   make(map[string]string),
 		ipUpdateChan: make(chan string, 10),
+		reloadChan:   make(chan struct{}, 1),
+	}
+
+	metrics.OnScrape(c.updateMetrics)
+	return c
+}
+
+// SetStateStore persists monitor state to store and restores it when monitors start
+func (c *Coordinator) SetStateStore(store *state.Store) {
+	c.store = store
+}
+
+// updateMetrics refreshes gauges derived from monitor state before a scrape
+func (c *Coordinator) updateMetrics() {
+	for _, state := range c.VMStates() {
+		metrics.VMStatusDuration.Set(time.Since(state.Since).Seconds(), state.Name)
 	}
 }
 
 // Start begins monitoring all VMs
 func (c *Coordinator) Start(ctx context.Context) {
-	if len(c.config.VMs) == 0 {
+	c.configHash, _ = fileHash(configPath)
+
+	// Watch vms.yaml even when it is empty so VMs can be added without a restart
+	c.wg.Add(1)
+	go c.configWatcher(ctx)
+
+	if len(c.config.VMs) == 0 && !c.config.Discovery.Enabled {
 		logger.Warn("No VMs configured for monitoring")
-		return
 	}
 
 	logger.Info("Starting VM monitoring",
@@ -48,38 +101,238 @@ func (c *Coordinator) Start(ctx context.Context) {
 
 	// Create monitors for each VM
 	for i := range c.config.VMs {
-		monitor := NewVMMonitor(
-			&c.config.VMs[i],
-			c.client,
-			c.notifier,
-			c.config.MinCheckInterval,
-			c.config.MaxCheckInterval,
-			&c.configMu,
-			c.ipUpdateChan,
-		)
-		c.monitors = append(c.monitors, monitor)
-
-		// Start each monitor in its own goroutine
-		c.wg.Add(1)
-		go func(m *VMMonitor) {
-			defer c.wg.Done()
-			m.Start(ctx)
-		}(monitor)
+		c.startMonitor(ctx, &c.config.VMs[i], c.ipUpdateChan)
 	}
 
 	// Start IP update saver
 	c.wg.Add(1)
 	go c.ipUpdateSaver(ctx)
 
+	if c.config.Discovery.Enabled {
+		c.wg.Add(1)
+		go c.discoveryLoop(ctx)
+	}
+
 	logger.Info("All VM monitors started")
 }
 
+// startMonitor creates a monitor for vm and runs it in its own goroutine.
+// IP changes are reported on ipUpdates (nil for VMs not stored in vms.yaml).
+func (c *Coordinator) startMonitor(ctx context.Context, vm *config.VM, ipUpdates chan string) *VMMonitor {
+	monitor := NewVMMonitor(
+		vm,
+		c.backendFor(vm),
+		c.notifier,
+		c.config.MinCheckInterval,
+		c.config.MaxCheckInterval,
+		&c.configMu,
+		ipUpdates,
+	)
+	monitor.SetPolicy(c.globalPolicy())
+	if c.store != nil {
+		monitor.UseStore(c.store)
+	}
+
+	monitorCtx, cancel := context.WithCancel(ctx)
+
+	c.monitorsMu.Lock()
+	c.monitors = append(c.monitors, monitor)
+	c.cancels[vm.Name] = cancel
+	c.monitorsMu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		monitor.Start(monitorCtx)
+	}()
+
+	return monitor
+}
+
+// stopMonitor stops and forgets the monitor of the named VM
+func (c *Coordinator) stopMonitor(name string) bool {
+	c.monitorsMu.Lock()
+	defer c.monitorsMu.Unlock()
+
+	cancel, ok := c.cancels[name]
+	if !ok {
+		return false
+	}
+	cancel()
+	delete(c.cancels, name)
+	metrics.ForgetVM(name)
+	if c.store != nil {
+		if err := c.store.Delete(name); err != nil {
+			logger.Error("Failed to delete VM state",
+				"vm", name,
+				"error", err,
+			)
+		}
+	}
+
+	for i, m := range c.monitors {
+		if m.Name() == name {
+			c.monitors = append(c.monitors[:i], c.monitors[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
 // Wait blocks until all monitors have stopped
 func (c *Coordinator) Wait() {
 	c.wg.Wait()
 	logger.Info("All VM monitors stopped")
 }
 
+func (c *Coordinator) globalPolicy() config.Policy {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	return c.config.Policy
+}
+
+// backendFor picks the API backend matching how the VM is configured
+func (c *Coordinator) backendFor(vm *config.VM) client.Backend {
+	if vm.UsesComputeAPI() {
+		return c.compute
+	}
+	return c.gateway
+}
+
+// VMStates returns snapshots of all monitored VMs in configuration order
+func (c *Coordinator) VMStates() []VMState {
+	c.monitorsMu.RLock()
+	defer c.monitorsMu.RUnlock()
+
+	states := make([]VMState, 0, len(c.monitors))
+	for _, m := range c.monitors {
+		states = append(states, m.State())
+	}
+	return states
+}
+
+// VMState returns the snapshot of a single VM
+func (c *Coordinator) VMState(name string) (VMState, bool) {
+	m := c.findMonitor(name)
+	if m == nil {
+		return VMState{}, false
+	}
+	return m.State(), true
+}
+
+// StartVM starts a VM on operator request
+func (c *Coordinator) StartVM(ctx context.Context, name string) error {
+	m := c.findMonitor(name)
+	if m == nil {
+		return ErrUnknownVM
+	}
+	return m.StartNow(ctx)
+}
+
+// MuteVM suppresses notifications for a VM for the given duration
+func (c *Coordinator) MuteVM(name string, d time.Duration) error {
+	m := c.findMonitor(name)
+	if m == nil {
+		return ErrUnknownVM
+	}
+	m.Mute(time.Now().Add(d))
+	return nil
+}
+
+// AcknowledgeVM records that an operator is handling the VM's open incident
+func (c *Coordinator) AcknowledgeVM(name, by string) error {
+	m := c.findMonitor(name)
+	if m == nil {
+		return ErrUnknownVM
+	}
+	return m.Acknowledge(by)
+}
+
+// PauseVM suspends automatic starts and failure alerts for a VM for the given duration
+func (c *Coordinator) PauseVM(name string, d time.Duration, by, reason string) error {
+	m := c.findMonitor(name)
+	if m == nil {
+		return ErrUnknownVM
+	}
+	m.Pause(time.Now().Add(d), by, reason)
+	return nil
+}
+
+// ResumeVM lifts a pause set by PauseVM
+func (c *Coordinator) ResumeVM(name string) error {
+	m := c.findMonitor(name)
+	if m == nil {
+		return ErrUnknownVM
+	}
+	if !m.Resume() {
+		return ErrNotPaused
+	}
+	return nil
+}
+
+// CheckVM schedules an immediate check of a VM
+func (c *Coordinator) CheckVM(name string) error {
+	m := c.findMonitor(name)
+	if m == nil {
+		return ErrUnknownVM
+	}
+	m.TriggerCheck()
+	return nil
+}
+
+// CheckMonitors reports monitor loops that have not ticked within a multiple of their interval
+func (c *Coordinator) CheckMonitors() error {
+	c.monitorsMu.RLock()
+	defer c.monitorsMu.RUnlock()
+
+	var stalled []string
+	for _, m := range c.monitors {
+		lastTick, interval, _ := m.Heartbeat()
+		if lastTick.IsZero() {
+			// Not started yet
+			continue
+		}
+		if since := time.Since(lastTick); since > heartbeatMultiple*interval+heartbeatSlack {
+			stalled = append(stalled, fmt.Sprintf("%s (no tick for %s)", m.Name(), since.Round(time.Second)))
+		}
+	}
+
+	if len(stalled) > 0 {
+		return fmt.Errorf("monitor loops stalled: %s", strings.Join(stalled, ", "))
+	}
+	return nil
+}
+
+// CheckReady reports monitors that have not completed their first check yet
+func (c *Coordinator) CheckReady() error {
+	c.monitorsMu.RLock()
+	defer c.monitorsMu.RUnlock()
+
+	var pending []string
+	for _, m := range c.monitors {
+		if _, _, lastCheck := m.Heartbeat(); lastCheck.IsZero() {
+			pending = append(pending, m.Name())
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("waiting for first check: %s", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+func (c *Coordinator) findMonitor(name string) *VMMonitor {
+	c.monitorsMu.RLock()
+	defer c.monitorsMu.RUnlock()
+
+	for _, m := range c.monitors {
+		if m.Name() == name {
+			return m
+		}
+	}
+	return nil
+}
+
 // ipUpdateSaver periodically saves IP updates to config file
 func (c *Coordinator) ipUpdateSaver(ctx context.Context) {
 	defer c.wg.Done()
@@ -103,22 +356,36 @@ func (c *Coordinator) ipUpdateSaver(ctx context.Context) {
 
 		case <-ticker.C:
 			if pendingUpdates {
-				c.saveConfig()
-				pendingUpdates = false
+				pendingUpdates = !c.saveConfig()
 			}
 		}
 	}
 }
 
-func (c *Coordinator) saveConfig() {
+// saveConfig writes the VM list back to vms.yaml. If the file was edited since
+// it was last read, saving would overwrite the edit: a reload is requested
+// instead and false is returned so the save is retried afterwards.
+func (c *Coordinator) saveConfig() bool {
+	if c.configChanged() {
+		logger.Info("vms.yaml was edited, reloading before saving IP updates")
+		c.RequestReload()
+		return false
+	}
+
+	monitors := c.staticMonitors()
+
 	c.configMu.Lock()
 	defer c.configMu.Unlock()
 
-	if err := c.config.SaveVMs("vms.yaml"); err != nil {
+	c.syncStaticVMs(monitors)
+	if err := c.config.SaveVMs(configPath); err != nil {
 		logger.Error("Failed to save VM config",
 			"error", err,
 		)
-	} else {
-		logger.Debug("VM config saved successfully")
+		return false
 	}
+
+	c.configHash, _ = fileHash(configPath)
+	logger.Debug("VM config saved successfully")
+	return true
 }
@@ -0,0 +1,55 @@
+package monitoring
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+)
+
+func testMonitor(name string, lastTick, lastCheck time.Time) *VMMonitor {
+	m := NewVMMonitor(&config.VM{Name: name}, nil, nil, 5*time.Second, 60*time.Second, &sync.Mutex{}, nil)
+	m.lastTick = lastTick
+	m.lastCheck = lastCheck
+	return m
+}
+
+func TestCoordinator_CheckMonitors(t *testing.T) {
+	now := time.Now()
+	c := &Coordinator{monitors: []*VMMonitor{
+		testMonitor("fresh", now, now),
+		testMonitor("not-started", time.Time{}, time.Time{}),
+	}}
+
+	if err := c.CheckMonitors(); err != nil {
+		t.Fatalf("expected healthy monitors, got %v", err)
+	}
+
+	// Unknown status is checked every 5s; ten minutes without a tick is a stall
+	c.monitors = append(c.monitors, testMonitor("wedged", now.Add(-10*time.Minute), now.Add(-10*time.Minute)))
+
+	err := c.CheckMonitors()
+	if err == nil || !strings.Contains(err.Error(), "wedged") || strings.Contains(err.Error(), "fresh") {
+		t.Fatalf("expected only wedged monitor to be reported, got %v", err)
+	}
+}
+
+func TestCoordinator_CheckReady(t *testing.T) {
+	now := time.Now()
+	c := &Coordinator{monitors: []*VMMonitor{
+		testMonitor("checked", now, now),
+		testMonitor("pending", now, time.Time{}),
+	}}
+
+	err := c.CheckReady()
+	if err == nil || !strings.Contains(err.Error(), "pending") || strings.Contains(err.Error(), "checked") {
+		t.Fatalf("expected pending monitor to be reported, got %v", err)
+	}
+
+	c.monitors[1].lastCheck = now
+	if err := c.CheckReady(); err != nil {
+		t.Fatalf("expected ready, got %v", err)
+	}
+}
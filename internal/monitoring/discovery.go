@@ -0,0 +1,184 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/client"
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// discoveryLoop periodically reconciles monitors with the instances
+// matching the discovery folder and labels
+func (c *Coordinator) discoveryLoop(ctx context.Context) {
+	defer c.wg.Done()
+
+	logger.Info("🔭 VM discovery enabled",
+		"folder", c.config.Discovery.FolderID,
+		"labels", c.config.Discovery.Labels,
+		"interval", c.config.Discovery.Interval,
+	)
+
+	c.discover(ctx)
+
+	ticker := time.NewTicker(c.config.Discovery.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.discover(ctx)
+		}
+	}
+}
+
+func (c *Coordinator) discover(ctx context.Context) {
+	instances, err := c.compute.ListInstances(ctx, c.config.Discovery.FolderID)
+	if err != nil {
+		// Keep current monitors: a failed listing says nothing about the VMs
+		logger.Error("❌ VM discovery failed",
+			"folder", c.config.Discovery.FolderID,
+			"error", err,
+		)
+		return
+	}
+
+	// VMs listed in vms.yaml take precedence over discovered ones
+	c.configMu.Lock()
+	static := make(map[string]bool, 2*len(c.config.VMs))
+	for _, vm := range c.config.VMs {
+		static[vm.Name] = true
+		if vm.InstanceID != "" {
+			static[vm.InstanceID] = true
+		}
+	}
+	c.configMu.Unlock()
+
+	var matched []client.Instance
+	for _, inst := range instances {
+		if static[inst.ID] || static[inst.Name] {
+			continue
+		}
+		if matchLabels(inst.Labels, c.config.Discovery.Labels) {
+			matched = append(matched, inst)
+		}
+	}
+
+	c.monitorsMu.RLock()
+	current := make(map[string]string, len(c.discovered))
+	for id, name := range c.discovered {
+		current[id] = name
+	}
+	c.monitorsMu.RUnlock()
+
+	added, removed := diffDiscovered(current, matched)
+
+	for _, inst := range added {
+		c.monitorsMu.Lock()
+		name := discoveredName(inst, func(name string) bool {
+			_, taken := c.cancels[name]
+			return taken
+		})
+		c.discovered[inst.ID] = name
+		c.monitorsMu.Unlock()
+
+		if name != inst.Name && inst.Name != "" {
+			logger.Warn("⚠️ Discovered VM name is already monitored, using a unique name",
+				"name", inst.Name,
+				"vm", name,
+				"instance_id", inst.ID,
+			)
+		}
+
+		vm := &config.VM{
+			Name:       name,
+			InstanceID: inst.ID,
+			IP:         inst.IP(),
+		}
+
+		logger.Info("🔭 Discovered VM, starting monitor",
+			"vm", vm.Name,
+			"instance_id", inst.ID,
+		)
+		c.startMonitor(ctx, vm, nil)
+		c.notifyDiscovery(vm.Name, fmt.Sprintf("🔭 Обнаружена новая ВМ *%s*, мониторинг запущен.", vm.Name))
+	}
+
+	for _, id := range removed {
+		name := current[id]
+
+		c.monitorsMu.Lock()
+		delete(c.discovered, id)
+		c.monitorsMu.Unlock()
+
+		logger.Info("🔭 VM no longer matches discovery, stopping monitor",
+			"vm", name,
+			"instance_id", id,
+		)
+		c.stopMonitor(name)
+		c.notifyDiscovery(name, fmt.Sprintf("🔭 ВМ *%s* удалена или потеряла метку, мониторинг остановлен.", name))
+	}
+}
+
+func (c *Coordinator) notifyDiscovery(vmName, message string) {
+	c.notifier.Enqueue(notification.Notification{
+		VMName:   vmName,
+		Status:   types.StatusUnknown,
+		Message:  message,
+		Priority: notification.PriorityLow,
+	})
+}
+
+// discoveredName returns the monitor name of a discovered instance. Instance
+// names are unique only within a folder, so a name that is already taken is
+// suffixed with the instance ID; an unnamed instance goes by its ID.
+func discoveredName(inst client.Instance, taken func(string) bool) string {
+	if inst.Name == "" {
+		return inst.ID
+	}
+	if taken(inst.Name) {
+		return inst.Name + "-" + inst.ID
+	}
+	return inst.Name
+}
+
+// diffDiscovered compares monitored instances (ID -> name) with the instances
+// currently matching discovery and returns what to start and what to stop
+func diffDiscovered(current map[string]string, matched []client.Instance) (added []client.Instance, removed []string) {
+	seen := make(map[string]bool, len(matched))
+	for _, inst := range matched {
+		seen[inst.ID] = true
+		if _, ok := current[inst.ID]; !ok {
+			added = append(added, inst)
+		}
+	}
+
+	for id := range current {
+		if !seen[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	return added, removed
+}
+
+// matchLabels reports whether labels satisfy every key=value of selector.
+// A selector value of "*" only requires the label to be present.
+func matchLabels(labels, selector map[string]string) bool {
+	for key, want := range selector {
+		got, ok := labels[key]
+		if !ok {
+			return false
+		}
+		if want != "*" && got != want {
+			return false
+		}
+	}
+	return true
+}
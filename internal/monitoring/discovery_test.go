@@ -0,0 +1,71 @@
+package monitoring
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/client"
+)
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"watchdog": "enabled", "env": "prod"}
+
+	tests := []struct {
+		name     string
+		selector map[string]string
+		expected bool
+	}{
+		{"empty selector", nil, true},
+		{"exact match", map[string]string{"watchdog": "enabled"}, true},
+		{"all keys", map[string]string{"watchdog": "enabled", "env": "prod"}, true},
+		{"wrong value", map[string]string{"watchdog": "disabled"}, false},
+		{"missing key", map[string]string{"team": "core"}, false},
+		{"wildcard", map[string]string{"env": "*"}, true},
+		{"wildcard missing", map[string]string{"team": "*"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchLabels(labels, tt.selector); got != tt.expected {
+				t.Errorf("matchLabels() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiffDiscovered(t *testing.T) {
+	current := map[string]string{"id1": "web", "id2": "db"}
+	matched := []client.Instance{
+		{ID: "id1", Name: "web"},
+		{ID: "id3", Name: "cache"},
+	}
+
+	added, removed := diffDiscovered(current, matched)
+
+	if len(added) != 1 || added[0].ID != "id3" {
+		t.Errorf("added = %+v, want id3", added)
+	}
+	sort.Strings(removed)
+	if len(removed) != 1 || removed[0] != "id2" {
+		t.Errorf("removed = %v, want [id2]", removed)
+	}
+}
+
+func TestDiscoveredName(t *testing.T) {
+	taken := func(name string) bool { return name == "web" }
+
+	tests := []struct {
+		inst     client.Instance
+		expected string
+	}{
+		{client.Instance{ID: "id1", Name: "db"}, "db"},
+		{client.Instance{ID: "id2", Name: "web"}, "web-id2"},
+		{client.Instance{ID: "id3"}, "id3"},
+	}
+
+	for _, tt := range tests {
+		if got := discoveredName(tt.inst, taken); got != tt.expected {
+			t.Errorf("discoveredName(%+v) = %q, want %q", tt.inst, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// escalate re-notifies about an open incident that nobody has acknowledged,
+// one escalation step at a time. Acknowledging the incident stops the ladder.
+func (m *VMMonitor) escalate() {
+	steps := m.policy().Escalation
+	if len(steps) == 0 || m.inMaintenanceNow() {
+		return
+	}
+
+	m.mu.Lock()
+	inc := m.incident
+	if inc == nil || inc.AcknowledgedBy != "" || inc.Escalations >= len(steps) {
+		m.mu.Unlock()
+		return
+	}
+	step := steps[inc.Escalations]
+	openFor := time.Since(inc.OpenedAt)
+	if openFor < step.After {
+		m.mu.Unlock()
+		return
+	}
+	inc.Escalations++
+	level, id, status := inc.Escalations, inc.ID, m.currentStatus
+	m.noteLocked("Эскалация %d: не принят %s", level, openFor.Round(time.Second))
+	m.mu.Unlock()
+	m.persist()
+
+	logger.Warn("⏫ Escalating unacknowledged incident",
+		"vm", m.vm.Name,
+		"incident", id,
+		"level", level,
+		"open_for", openFor.Round(time.Second),
+		"channels", step.Channels,
+	)
+
+	message := fmt.Sprintf("⏫ ЭСКАЛАЦИЯ %d: инцидент `%s` на ВМ *%s* никто не принял за %s.\n\nСтатус: %s",
+		level, id, m.vm.Name, openFor.Round(time.Second), status)
+	if m.isAppDown() {
+		message += "\nПроверки приложения не проходят."
+	}
+	m.notifier.Enqueue(notification.Notification{
+		VMName:     m.vm.Name,
+		Status:     status,
+		Message:    message,
+		Priority:   notification.PriorityCritical,
+		Keyboard:   notification.NewAlertKeyboard(m.vm.Name),
+		Incident:   id,
+		Escalation: level,
+		Mentions:   step.Mention,
+		Channels:   step.Channels,
+	})
+}
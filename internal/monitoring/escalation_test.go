@@ -0,0 +1,60 @@
+package monitoring
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestVMMonitor_Escalation(t *testing.T) {
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusStopped}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	vm := &config.VM{Name: "vm-1", DesiredState: types.DesiredUnmanaged}
+	m := NewVMMonitor(vm, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.SetPolicy(config.Policy{Escalation: []config.EscalationStep{
+		{After: time.Nanosecond, Mention: []string{"@alice"}},
+		{After: 2 * time.Nanosecond, Channels: []string{"email"}},
+		{After: 3 * time.Nanosecond, Mention: []string{"@bob"}},
+	}})
+
+	m.check(context.Background()) // opens the incident
+	m.check(context.Background()) // level 1
+	m.check(context.Background()) // level 2
+	if got := m.State().Incident; got == nil || got.Escalations != 2 {
+		t.Fatalf("expected two escalations, got %+v", got)
+	}
+
+	// Acknowledging stops the ladder before level 3
+	if err := m.Acknowledge("alice"); err != nil {
+		t.Fatal(err)
+	}
+	m.check(context.Background())
+
+	queue.Stop()
+	var escalations []notification.Notification
+	for _, n := range sent.notifications() {
+		if n.Escalation > 0 {
+			escalations = append(escalations, n)
+		}
+	}
+	if len(escalations) != 2 {
+		t.Fatalf("expected two escalations, got %d", len(escalations))
+	}
+	first, second := escalations[0], escalations[1]
+	if first.Escalation != 1 || !slices.Equal(first.Mentions, []string{"@alice"}) || first.Incident != m.State().Incident.ID {
+		t.Errorf("unexpected first escalation %+v", first)
+	}
+	if second.Escalation != 2 || !slices.Equal(second.Channels, []string{"email"}) {
+		t.Errorf("unexpected second escalation %+v", second)
+	}
+}
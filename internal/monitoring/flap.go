@@ -0,0 +1,166 @@
+package monitoring
+
+import (
+	"fmt"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// flapHistory keeps whether the VM was up at each of its last checks, oldest first
+type flapHistory []bool
+
+// record adds a check result, keeping the last samples. An empty history is
+// filled with the first result so a new VM does not start out flapping.
+func (h flapHistory) record(up bool, samples int) flapHistory {
+	if len(h) == 0 {
+		h = make(flapHistory, samples-1, samples)
+		for i := range h {
+			h[i] = up
+		}
+	}
+	for len(h) < samples-1 {
+		h = append(flapHistory{h[0]}, h...)
+	}
+	h = append(h, up)
+	return h[len(h)-samples:]
+}
+
+// percentChange is the weighted percentage of checks that changed state.
+// As in Nagios, recent changes weigh 1.2 and the oldest 0.8.
+func (h flapHistory) percentChange() float64 {
+	transitions := len(h) - 1
+	if transitions < 1 {
+		return 0
+	}
+
+	var weighted float64
+	for i := 1; i < len(h); i++ {
+		if h[i] == h[i-1] {
+			continue
+		}
+		weight := 1.0
+		if transitions > 1 {
+			weight = 0.8 + 0.4*float64(i-1)/float64(transitions-1)
+		}
+		weighted += weight
+	}
+	return weighted / float64(transitions) * 100
+}
+
+// recordFlap adds the outcome of a check to the VM's history and announces
+// when the VM starts and stops flapping
+func (m *VMMonitor) recordFlap() {
+	samples, high, low := m.policy().FlapThresholds()
+	if samples == 0 || m.expectsDown() {
+		return
+	}
+
+	m.mu.Lock()
+	status := m.currentStatus
+	if status == types.StatusUnknown {
+		m.mu.Unlock()
+		return
+	}
+	up := status == types.StatusRunning && !m.appDown
+	m.flapHistory = m.flapHistory.record(up, samples)
+	percent := m.flapHistory.percentChange()
+	m.flapPercent = percent
+
+	was := m.flapping
+	switch {
+	case !was && percent > high:
+		m.flapping = true
+	case was && percent < low:
+		m.flapping = false
+	}
+	now := m.flapping
+	open := m.incident != nil
+	var incidentID string
+	switch {
+	case open:
+		incidentID = m.incident.ID
+	case m.lastIncident != nil:
+		// Its recovery was suppressed: the end of flapping closes it
+		incidentID = m.lastIncident.ID
+	}
+	switch {
+	case now && !was:
+		m.noteLocked("Флаппинг начался (%.0f%% изменений)", percent)
+	case was && !now:
+		m.noteLocked("Флаппинг закончился")
+	}
+	m.mu.Unlock()
+
+	if was == now {
+		return
+	}
+	setFlappingMetric(m.vm.Name, now)
+
+	if now {
+		logger.Warn("🔀 VM is flapping, suppressing alerts",
+			"vm", m.vm.Name,
+			"percent_change", fmt.Sprintf("%.1f", percent),
+		)
+		message := fmt.Sprintf("🔀 ФЛАППИНГ: ВМ *%s* постоянно меняет состояние (%.0f%% изменений за последние проверки).\n\n"+
+			"Оповещения о сбоях и восстановлениях приостановлены, автозапуск продолжает работать.", m.vm.Name, percent)
+		m.notifier.Enqueue(notification.Notification{
+			VMName:   m.vm.Name,
+			Status:   status,
+			Message:  message,
+			Priority: notification.PriorityCritical,
+			Keyboard: notification.NewAlertKeyboard(m.vm.Name),
+			Incident: m.incidentID(),
+		})
+		return
+	}
+
+	logger.Info("🔀 VM stopped flapping",
+		"vm", m.vm.Name,
+		"percent_change", fmt.Sprintf("%.1f", percent),
+		"status", status,
+	)
+	healthy := status == types.StatusRunning && !open
+	message := fmt.Sprintf("✅ ВМ *%s* больше не флапает (%.0f%% изменений). Текущий статус: %s.", m.vm.Name, percent, status)
+	n := notification.Notification{
+		VMName:   m.vm.Name,
+		Status:   status,
+		Message:  message,
+		Priority: notification.PriorityCritical,
+		Incident: incidentID,
+		// Recovery messages were suppressed: close the incident here
+		Resolve: healthy,
+	}
+	if !healthy {
+		n.Message += "\nСбой продолжается, оповещения возобновлены."
+		n.Keyboard = notification.NewAlertKeyboard(m.vm.Name)
+	}
+	m.notifier.Enqueue(n)
+}
+
+// notify sends a failure, start or recovery notification unless the VM is
+// flapping, in which case the flapping notices stand in for them
+func (m *VMMonitor) notify(n notification.Notification) {
+	m.mu.RLock()
+	flapping := m.flapping
+	m.mu.RUnlock()
+
+	if flapping {
+		logger.Debug("Skipping notification for flapping VM",
+			"vm", m.vm.Name,
+			"status", n.Status,
+		)
+		return
+	}
+	m.notifier.Enqueue(n)
+}
+
+func setFlappingMetric(vmName string, flapping bool) {
+	value := 0.0
+	if flapping {
+		value = 1
+	}
+	metrics.VMFlapping.Set(value, vmName)
+}
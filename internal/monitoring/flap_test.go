@@ -0,0 +1,96 @@
+package monitoring
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestFlapHistory(t *testing.T) {
+	var h flapHistory
+	h = h.record(true, 5)
+	if len(h) != 5 || h.percentChange() != 0 {
+		t.Fatalf("expected a steady history of 5 samples, got %v", h)
+	}
+
+	h = h.record(false, 5)
+	// A single change, on the newest transition
+	if got := h.percentChange(); math.Abs(got-30) > 0.01 {
+		t.Errorf("expected 30%%, got %.2f", got)
+	}
+
+	for _, up := range []bool{true, false, true, false} {
+		h = h.record(up, 5)
+	}
+	if got := h.percentChange(); math.Abs(got-100) > 0.01 {
+		t.Errorf("expected 100%% for an alternating history, got %.2f", got)
+	}
+
+	// A larger sample size keeps the history and pads it with the oldest result
+	h = h.record(false, 7)
+	if len(h) != 7 {
+		t.Errorf("expected 7 samples, got %d", len(h))
+	}
+}
+
+func TestVMMonitor_Flapping(t *testing.T) {
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusRunning}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	m := NewVMMonitor(&config.VM{Name: "vm-1"}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.SetPolicy(config.Policy{
+		GracePeriod:   time.Nanosecond,
+		RestartBudget: config.RestartBudget{MaxStarts: 100},
+		Flapping:      config.Flapping{Samples: 5, High: 50, Low: 25},
+	})
+
+	statuses := []types.VMStatus{
+		types.StatusRunning, types.StatusStopped, types.StatusRunning, // starts flapping
+		types.StatusStopped, types.StatusRunning, // suppressed
+	}
+	for _, status := range statuses {
+		backend.setStatus(status)
+		m.check(context.Background())
+		time.Sleep(time.Millisecond)
+	}
+	if !m.State().Flapping {
+		t.Fatal("expected the VM to be flapping")
+	}
+	if backend.startCount() != 2 {
+		t.Errorf("expected the monitor to keep starting the VM, got %d starts", backend.startCount())
+	}
+
+	// Settles down
+	for i := 0; i < 3; i++ {
+		m.check(context.Background())
+	}
+	if m.State().Flapping {
+		t.Fatal("expected the VM to stop flapping")
+	}
+
+	queue.Stop()
+	for substr, want := range map[string]int{
+		"СБОЙ":              1,
+		"ВОССТАНОВЛЕНИЕ":    1,
+		"Автозапуск":        1,
+		"ФЛАППИНГ":          1,
+		"больше не флапает": 1,
+	} {
+		if got := countMessages(sent, substr); got != want {
+			t.Errorf("expected %d messages containing %q, got %d", want, substr, got)
+		}
+	}
+	notifications := sent.notifications()
+	if last := notifications[len(notifications)-1]; !last.Resolve {
+		t.Errorf("expected the end of flapping to close the incident, got %+v", last)
+	}
+}
@@ -0,0 +1,198 @@
+package monitoring
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/state"
+)
+
+// ErrNoIncident is returned when acknowledging a VM without an open incident
+var ErrNoIncident = errors.New("no open incident")
+
+// maxIncidentEvents bounds an incident's timeline; the opening event is always kept
+const maxIncidentEvents = 50
+
+// Incident is a failure of a VM from the first alert to its recovery
+type Incident struct {
+	ID             string
+	OpenedAt       time.Time
+	AcknowledgedBy string
+	AcknowledgedAt time.Time
+	ResolvedAt     time.Time // zero while the incident is open
+	Escalations    int       // escalation steps taken while nobody acknowledged it
+	Timeline       []IncidentEvent
+}
+
+// IncidentEvent is an entry in an incident's timeline
+type IncidentEvent struct {
+	At   time.Time
+	Text string
+}
+
+// Downtime is how long the incident lasted, or has lasted so far while it is open
+func (i *Incident) Downtime() time.Duration {
+	end := i.ResolvedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(i.OpenedAt)
+}
+
+func (i *Incident) clone() *Incident {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	c.Timeline = append([]IncidentEvent(nil), i.Timeline...)
+	return &c
+}
+
+// newIncidentID derives a stable, readable ID from the VM and the opening time
+func newIncidentID(vmName string, openedAt time.Time) string {
+	return fmt.Sprintf("%s-%s", vmName, openedAt.UTC().Format("20060102-150405"))
+}
+
+func appendEvent(events []IncidentEvent, e IncidentEvent) []IncidentEvent {
+	events = append(events, e)
+	if len(events) > maxIncidentEvents {
+		events = append(events[:1], events[len(events)-maxIncidentEvents+1:]...)
+	}
+	return events
+}
+
+// openIncidentLocked opens an incident unless one is open. Events noted
+// earlier in the same check become the start of its timeline.
+func (m *VMMonitor) openIncidentLocked(at time.Time) {
+	if m.incident != nil {
+		return
+	}
+
+	id := newIncidentID(m.vm.Name, at)
+	if m.lastIncident != nil && m.lastIncident.ID == id {
+		// Reopened within the same second
+		id += "-2"
+	}
+	m.incident = &Incident{
+		ID:       id,
+		OpenedAt: at,
+		Timeline: m.pendingEvents,
+	}
+	m.pendingEvents = nil
+}
+
+// resolveIncidentLocked closes the open incident and returns a copy of it,
+// or nil if no incident was open
+func (m *VMMonitor) resolveIncidentLocked(at time.Time) *Incident {
+	m.pendingEvents = nil
+	if m.incident == nil {
+		return nil
+	}
+
+	m.incident.ResolvedAt = at
+	m.incident.Timeline = appendEvent(m.incident.Timeline, IncidentEvent{At: at, Text: "Восстановлена"})
+	m.lastIncident = m.incident
+	m.incident = nil
+	return m.lastIncident.clone()
+}
+
+// note adds an event to the open incident's timeline. Without one the event
+// is kept until the end of the check, for an incident the check may open.
+func (m *VMMonitor) note(format string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.noteLocked(format, args...)
+}
+
+// noteLocked is note for callers holding m.mu
+func (m *VMMonitor) noteLocked(format string, args ...any) {
+	e := IncidentEvent{At: time.Now(), Text: fmt.Sprintf(format, args...)}
+	if m.incident != nil {
+		m.incident.Timeline = appendEvent(m.incident.Timeline, e)
+		return
+	}
+	m.pendingEvents = appendEvent(m.pendingEvents, e)
+}
+
+// incidentID returns the ID of the open incident, or "" without one
+func (m *VMMonitor) incidentID() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.incident == nil {
+		return ""
+	}
+	return m.incident.ID
+}
+
+// Acknowledge records that an operator has taken ownership of the open incident
+func (m *VMMonitor) Acknowledge(by string) error {
+	m.mu.Lock()
+	if m.incident == nil {
+		m.mu.Unlock()
+		return ErrNoIncident
+	}
+	m.incident.AcknowledgedBy = by
+	m.incident.AcknowledgedAt = time.Now()
+	m.noteLocked("Принято: %s", by)
+	m.mu.Unlock()
+
+	m.persist()
+	return nil
+}
+
+// recoveryDetails is the line a recovery message adds about the incident it closes
+func recoveryDetails(resolved *Incident) string {
+	if resolved == nil {
+		return ""
+	}
+	return fmt.Sprintf("\nПростой: %s (инцидент `%s`)", resolved.Downtime().Round(time.Second), resolved.ID)
+}
+
+// incidentKey returns the ID that groups a recovery with its incident's notifications
+func incidentKey(resolved *Incident) string {
+	if resolved == nil {
+		return ""
+	}
+	return resolved.ID
+}
+
+// restoreIncident rebuilds the open incident from a saved record. Records
+// written before incidents had IDs get one derived from their start.
+func restoreIncident(vmName string, rec state.VMRecord) *Incident {
+	if rec.IncidentStart.IsZero() {
+		return nil
+	}
+
+	id := rec.IncidentID
+	if id == "" {
+		id = newIncidentID(vmName, rec.IncidentStart)
+	}
+	inc := &Incident{
+		ID:             id,
+		OpenedAt:       rec.IncidentStart,
+		AcknowledgedBy: rec.AcknowledgedBy,
+		AcknowledgedAt: rec.AcknowledgedAt,
+		Escalations:    rec.IncidentEscalations,
+	}
+	for _, e := range rec.IncidentTimeline {
+		inc.Timeline = append(inc.Timeline, IncidentEvent{At: e.At, Text: e.Text})
+	}
+	return inc
+}
+
+// saveIncident writes the open incident into a record
+func saveIncident(inc *Incident, rec *state.VMRecord) {
+	if inc == nil {
+		return
+	}
+
+	rec.IncidentID = inc.ID
+	rec.IncidentStart = inc.OpenedAt
+	rec.AcknowledgedBy = inc.AcknowledgedBy
+	rec.AcknowledgedAt = inc.AcknowledgedAt
+	rec.IncidentEscalations = inc.Escalations
+	for _, e := range inc.Timeline {
+		rec.IncidentTimeline = append(rec.IncidentTimeline, state.IncidentEvent{At: e.At, Text: e.Text})
+	}
+}
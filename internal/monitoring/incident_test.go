@@ -0,0 +1,89 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/state"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestVMMonitor_IncidentLifecycle(t *testing.T) {
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusStopped}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	m := NewVMMonitor(&config.VM{Name: "vm-1"}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.SetPolicy(config.Policy{GracePeriod: time.Nanosecond})
+	m.UseStore(store)
+
+	if err := m.Acknowledge("alice"); !errors.Is(err, ErrNoIncident) {
+		t.Errorf("expected ErrNoIncident without an incident, got %v", err)
+	}
+
+	// Stopped: an incident opens and the VM is started
+	m.check(context.Background())
+	incident := m.State().Incident
+	if incident == nil || !strings.HasPrefix(incident.ID, "vm-1-") {
+		t.Fatalf("expected an open incident, got %+v", incident)
+	}
+	if err := m.Acknowledge("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The incident survives a restart of the process
+	restored := NewVMMonitor(&config.VM{Name: "vm-1"}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	restored.UseStore(store)
+	if got := restored.State().Incident; got == nil || got.ID != incident.ID || got.AcknowledgedBy != "alice" {
+		t.Errorf("expected the incident to be restored, got %+v", got)
+	}
+
+	time.Sleep(time.Millisecond)
+	backend.setStatus(types.StatusRunning)
+	m.check(context.Background())
+
+	got := m.State()
+	if got.Incident != nil || got.LastIncident == nil || got.LastIncident.ResolvedAt.IsZero() {
+		t.Fatalf("expected the incident to be resolved, got %+v", got)
+	}
+	var timeline []string
+	for _, e := range got.LastIncident.Timeline {
+		timeline = append(timeline, e.Text)
+	}
+	want := "Статус: Stopped, Запуск ВМ (auto), Принято: alice, Статус: Running, Восстановлена"
+	if strings.Join(timeline, ", ") != want {
+		t.Errorf("unexpected timeline:\n got: %s\nwant: %s", strings.Join(timeline, ", "), want)
+	}
+
+	queue.Stop()
+	notifications := sent.notifications()
+	if len(notifications) != 3 {
+		t.Fatalf("expected alert, start and recovery, got %d notifications", len(notifications))
+	}
+	for _, n := range notifications {
+		if n.Incident != incident.ID {
+			t.Errorf("expected %q to reference incident %s, got %q", n.Message, incident.ID, n.Incident)
+		}
+	}
+	if !strings.Contains(notifications[0].Message, incident.ID) {
+		t.Errorf("expected the alert to name the incident, got %q", notifications[0].Message)
+	}
+	recovery := notifications[2]
+	if !recovery.Resolve || !strings.Contains(recovery.Message, "Простой: ") {
+		t.Errorf("expected the recovery to resolve the incident and state the downtime, got %+v", recovery)
+	}
+}
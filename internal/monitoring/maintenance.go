@@ -0,0 +1,204 @@
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/schedule"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// Pause suspends automatic starts and failure alerts until the given time.
+// The VM's status is still tracked while it is paused.
+func (m *VMMonitor) Pause(until time.Time, by, reason string) {
+	m.mu.Lock()
+	m.pausedUntil = until
+	m.pausedBy = by
+	m.pauseReason = reason
+	m.inMaintenance = true
+	m.mu.Unlock()
+
+	logger.Info("⏸️ VM paused",
+		"vm", m.vm.Name,
+		"until", until.Format(time.RFC3339),
+		"by", by,
+		"reason", reason,
+	)
+	m.persist()
+}
+
+// Mute suppresses the VM's notifications until the given time. The mute is
+// saved with the rest of the state, so it outlives a restart.
+func (m *VMMonitor) Mute(until time.Time) {
+	m.mu.Lock()
+	m.mutedUntil = until
+	m.mu.Unlock()
+
+	m.notifier.Mute(m.vm.Name, until)
+	logger.Info("🔕 VM notifications muted",
+		"vm", m.vm.Name,
+		"until", until.Format(time.RFC3339),
+	)
+	m.persist()
+}
+
+// Resume lifts a pause before it expires. It returns false if the VM was not paused.
+// A maintenance window that is still active keeps the VM in maintenance.
+func (m *VMMonitor) Resume() bool {
+	m.mu.Lock()
+	if m.pausedUntil.IsZero() {
+		m.mu.Unlock()
+		return false
+	}
+	m.pausedUntil = time.Time{}
+	m.pausedBy = ""
+	m.pauseReason = ""
+	m.mu.Unlock()
+
+	logger.Info("▶️ VM resumed",
+		"vm", m.vm.Name,
+	)
+
+	if active, _, _ := m.maintenance(time.Now()); !active {
+		m.endMaintenance()
+	}
+	m.persist()
+	m.TriggerCheck()
+	return true
+}
+
+// maintenance reports whether the VM is paused or inside a maintenance window,
+// until when, and why
+func (m *VMMonitor) maintenance(now time.Time) (bool, time.Time, string) {
+	m.mu.RLock()
+	pausedUntil, pausedBy, pauseReason := m.pausedUntil, m.pausedBy, m.pauseReason
+	m.mu.RUnlock()
+
+	if now.Before(pausedUntil) {
+		reason := "пауза от " + pausedBy
+		if pauseReason != "" {
+			reason += ": " + pauseReason
+		}
+		return true, pausedUntil, reason
+	}
+
+	for _, w := range m.maintenanceWindows() {
+		if active, until := w.Active(now); active {
+			return true, until, "окно обслуживания"
+		}
+	}
+	return false, time.Time{}, ""
+}
+
+// maintenanceWindows returns the parsed windows of the VM, parsing them once per configuration
+func (m *VMMonitor) maintenanceWindows() []*schedule.Window {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+
+	if m.windows != nil || len(m.vm.Maintenance) == 0 {
+		return m.windows
+	}
+
+	for _, mw := range m.vm.Maintenance {
+		w, err := mw.Window()
+		if err != nil {
+			// Validated when the configuration was loaded
+			logger.Error("Invalid maintenance window",
+				"vm", m.vm.Name,
+				"error", err,
+			)
+			continue
+		}
+		m.windows = append(m.windows, w)
+	}
+	return m.windows
+}
+
+// updateMaintenance refreshes the maintenance flag at the start of a check and
+// announces windows opening and closing
+func (m *VMMonitor) updateMaintenance() {
+	now := time.Now()
+	active, until, reason := m.maintenance(now)
+
+	m.mu.Lock()
+	was := m.inMaintenance
+	m.inMaintenance = active
+	pauseExpired := !m.pausedUntil.IsZero() && !now.Before(m.pausedUntil)
+	if pauseExpired {
+		m.pausedUntil = time.Time{}
+		m.pausedBy = ""
+		m.pauseReason = ""
+	}
+	m.mu.Unlock()
+
+	if pauseExpired {
+		m.persist()
+	}
+
+	switch {
+	case active && !was:
+		logger.Info("🔧 VM maintenance started",
+			"vm", m.vm.Name,
+			"until", until.Format(time.RFC3339),
+			"reason", reason,
+		)
+		m.notifyMaintenance(fmt.Sprintf("🔧 ВМ *%s* на обслуживании до %s (%s).\n\nАвтозапуск и оповещения о сбоях приостановлены.",
+			m.vm.Name, until.Format("02.01 15:04 MST"), reason))
+
+	case !active && was:
+		m.endMaintenance()
+		m.notifyMaintenance(fmt.Sprintf("▶️ Обслуживание ВМ *%s* завершено, автозапуск и оповещения возобновлены.", m.vm.Name))
+	}
+}
+
+// endMaintenance leaves maintenance. The status is reset so the next check
+// acts on it as on a fresh observation, e.g. starts a VM that is still stopped.
+func (m *VMMonitor) endMaintenance() {
+	m.mu.Lock()
+	m.inMaintenance = false
+	m.mu.Unlock()
+
+	logger.Info("🔧 VM maintenance ended",
+		"vm", m.vm.Name,
+	)
+	m.setStatus(types.StatusUnknown)
+}
+
+// inMaintenanceNow reports the maintenance flag as of the current check
+func (m *VMMonitor) inMaintenanceNow() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inMaintenance
+}
+
+// hasIncident reports whether an incident is open
+func (m *VMMonitor) hasIncident() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.incident != nil
+}
+
+// announceRecovery reports whether a transition to Running from oldStatus is
+// worth a notification: not on startup, and while the VM is expected to be
+// down only to close an incident that was opened before
+func (m *VMMonitor) announceRecovery(oldStatus types.VMStatus, hadIncident bool) bool {
+	if m.isAppDown() {
+		// The VM is back, but its application is not: probes announce the recovery
+		return false
+	}
+	if hadIncident {
+		return true
+	}
+	return oldStatus != types.StatusUnknown && !m.expectsDown()
+}
+
+func (m *VMMonitor) notifyMaintenance(message string) {
+	m.notifier.Enqueue(notification.Notification{
+		VMName:   m.vm.Name,
+		Status:   types.StatusUnknown,
+		Message:  message,
+		Priority: notification.PriorityLow,
+	})
+}
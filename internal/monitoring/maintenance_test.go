@@ -0,0 +1,172 @@
+package monitoring
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/client"
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/state"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+// fakeBackend reports a fixed status and counts start, stop and restart requests
+type fakeBackend struct {
+	mu       sync.Mutex
+	status   types.VMStatus
+	starts   int
+	stops    int
+	restarts int
+}
+
+func (b *fakeBackend) GetVMInfo(ctx context.Context, target string) (*client.VMInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &client.VMInfo{Status: b.status}, nil
+}
+
+func (b *fakeBackend) StartVM(ctx context.Context, target string) (*client.StartVMResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.starts++
+	return &client.StartVMResponse{Success: true}, nil
+}
+
+func (b *fakeBackend) StopVM(ctx context.Context, target string) (*client.StopVMResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stops++
+	return &client.StopVMResponse{Success: true}, nil
+}
+
+func (b *fakeBackend) RestartVM(ctx context.Context, target string) (*client.RestartVMResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.restarts++
+	return &client.RestartVMResponse{Success: true}, nil
+}
+
+func (b *fakeBackend) restartCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.restarts
+}
+
+func (b *fakeBackend) setStatus(status types.VMStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status = status
+}
+
+func (b *fakeBackend) stopCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stops
+}
+
+func (b *fakeBackend) startCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.starts
+}
+
+func TestVMMonitor_Pause(t *testing.T) {
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusStopped}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	m := NewVMMonitor(&config.VM{Name: "vm-1"}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.setStatus(types.StatusRunning)
+	m.Pause(time.Now().Add(time.Hour), "@oncall", "disk resize")
+
+	// Stopped on purpose: tracked, but neither started nor alerted
+	m.check(context.Background())
+	if got := m.State(); got.Status != types.StatusStopped || !got.IncidentStart.IsZero() {
+		t.Errorf("expected Stopped without an incident, got %s (incident %v)", got.Status, got.IncidentStart)
+	}
+	if backend.startCount() != 0 {
+		t.Errorf("expected no start during pause, got %d", backend.startCount())
+	}
+
+	// Resuming acts on the VM that is still stopped
+	if !m.Resume() {
+		t.Fatal("expected Resume to report a pause")
+	}
+	if m.Resume() {
+		t.Error("expected a second Resume to report no pause")
+	}
+	m.check(context.Background())
+	if backend.startCount() != 1 {
+		t.Errorf("expected one start after resume, got %d", backend.startCount())
+	}
+
+	queue.Stop()
+	alerts := 0
+	for _, n := range sent.notifications() {
+		if strings.Contains(n.Message, "СБОЙ") {
+			alerts++
+		}
+	}
+	if alerts != 1 {
+		t.Errorf("expected one failure alert after resume, got %d", alerts)
+	}
+}
+
+func TestVMMonitor_MaintenanceWindow(t *testing.T) {
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusStopped}
+	queue := notification.NewNotificationQueue(1)
+
+	vm := &config.VM{Name: "vm-1", Maintenance: []config.MaintenanceWindow{
+		{Cron: "* * * * *", Duration: time.Hour},
+	}}
+	m := NewVMMonitor(vm, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+
+	m.check(context.Background())
+	if !m.State().InMaintenance {
+		t.Error("expected the VM to be in maintenance")
+	}
+	if backend.startCount() != 0 {
+		t.Errorf("expected no start during a maintenance window, got %d", backend.startCount())
+	}
+
+	// Dropping the window from the configuration ends maintenance on the next check
+	m.UpdateVM(config.VM{Name: "vm-1"}, backend)
+	m.check(context.Background())
+	if m.State().InMaintenance {
+		t.Error("expected maintenance to end")
+	}
+	if backend.startCount() != 1 {
+		t.Errorf("expected a start once maintenance ended, got %d", backend.startCount())
+	}
+}
+
+func TestVMMonitor_MuteSurvivesRestart(t *testing.T) {
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var configMu sync.Mutex
+	queue := notification.NewNotificationQueue(1)
+	m := NewVMMonitor(&config.VM{Name: "vm-1"}, nil, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.UseStore(store)
+
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	m.Mute(until)
+
+	// A restarted process keeps the VM muted
+	restarted := notification.NewNotificationQueue(1)
+	restored := NewVMMonitor(&config.VM{Name: "vm-1"}, nil, restarted, 5*time.Second, 60*time.Second, &configMu, nil)
+	restored.UseStore(store)
+	if got := restarted.MutedUntil("vm-1"); !got.Equal(until) {
+		t.Errorf("expected the mute until %v to be restored, got %v", until, got)
+	}
+}
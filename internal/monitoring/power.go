@@ -0,0 +1,268 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/client"
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/schedule"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// stopTrigger says why a VM is being stopped
+type stopTrigger string
+
+const (
+	stopScheduled stopTrigger = "schedule"
+	stopDrift     stopTrigger = "desired_state"
+	stopStuck     stopTrigger = "stuck"
+)
+
+const (
+	// operationPollInterval is how often the operation of a power action is polled
+	operationPollInterval = 5 * time.Second
+	// operationTimeout bounds how long the operation of a power action is followed
+	operationTimeout = 10 * time.Minute
+)
+
+// operationNames names power actions in notifications
+var operationNames = map[string]string{
+	"start":   "запуска",
+	"stop":    "остановки",
+	"restart": "перезапуска",
+}
+
+// powerHours returns the parsed power schedule of the VM, or nil without one
+func (m *VMMonitor) powerHours() *schedule.Hours {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+
+	if m.vm.Schedule == nil || m.hours != nil {
+		return m.hours
+	}
+
+	hours, err := m.vm.Schedule.Hours()
+	if err != nil {
+		// Validated when the configuration was loaded
+		logger.Error("Invalid power schedule",
+			"vm", m.vm.Name,
+			"error", err,
+		)
+		return nil
+	}
+	m.hours = hours
+	return hours
+}
+
+// updateDesired works out the state the VM should be in at the start of a check:
+// the configured desired state, or stopped outside the power schedule
+func (m *VMMonitor) updateDesired() {
+	m.configMu.Lock()
+	desired := m.vm.Desired()
+	m.configMu.Unlock()
+
+	if hours := m.powerHours(); hours != nil && !hours.On(time.Now()) {
+		desired = types.DesiredStopped
+	}
+
+	m.mu.Lock()
+	changed := m.desired != desired
+	m.desired = desired
+	m.mu.Unlock()
+
+	if changed {
+		logger.Info("🎯 Desired VM state changed",
+			"vm", m.vm.Name,
+			"desired", desired,
+		)
+	}
+}
+
+// desiredState returns the desired state as of the current check
+func (m *VMMonitor) desiredState() types.DesiredState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.desired
+}
+
+// expectsDown reports whether the VM being down is expected right now:
+// it is in maintenance or desired to be stopped
+func (m *VMMonitor) expectsDown() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.expectsDownLocked()
+}
+
+// expectsDownLocked is expectsDown for callers holding m.mu
+func (m *VMMonitor) expectsDownLocked() bool {
+	return m.inMaintenance || m.desired == types.DesiredStopped
+}
+
+// reconcile starts or stops a VM whose observed status differs from the desired
+// state in ways the regular status handling does not cover: a running VM that
+// should be stopped, and a stopped VM whose schedule has begun. A VM that went
+// down with an open incident is left to the restart budget instead.
+func (m *VMMonitor) reconcile(ctx context.Context) {
+	scheduled := m.powerHours() != nil
+
+	m.mu.RLock()
+	desired, status := m.desired, m.currentStatus
+	incident := m.incident != nil
+	waiting := time.Now().Before(m.gracePeriodUntil)
+	lastAction := m.powerActionAt
+	maintenance := m.inMaintenance
+	m.mu.RUnlock()
+
+	// Give the previous action time to take effect
+	if maintenance || waiting || time.Since(lastAction) < m.policy().Grace() {
+		return
+	}
+
+	switch {
+	case desired == types.DesiredStopped && status == types.StatusRunning:
+		m.markPowerAction()
+		if scheduled {
+			_ = m.stopVM(ctx, stopScheduled)
+		} else {
+			_ = m.stopVM(ctx, stopDrift)
+		}
+
+	case desired == types.DesiredRunning && status == types.StatusStopped && scheduled && !incident:
+		m.markPowerAction()
+		_ = m.startVM(ctx, startScheduled)
+	}
+}
+
+func (m *VMMonitor) markPowerAction() {
+	m.mu.Lock()
+	m.powerActionAt = time.Now()
+	m.mu.Unlock()
+}
+
+// stopVM issues a stop request for a VM that should not be running
+func (m *VMMonitor) stopVM(ctx context.Context, trigger stopTrigger) error {
+	vmName := m.vm.Name
+	logger.Info("⏹️ Stopping VM",
+		"vm", vmName,
+		"trigger", trigger,
+	)
+
+	if trigger == stopDrift {
+		m.notifier.Enqueue(notification.Notification{
+			VMName:   vmName,
+			Status:   types.StatusRunning,
+			Message:  fmt.Sprintf("⚠️ РАСХОЖДЕНИЕ: ВМ *%s* запущена, хотя должна быть выключена (desired_state: stopped). Останавливаю.", vmName),
+			Priority: notification.PriorityCritical,
+		})
+	}
+
+	var resp *client.StopVMResponse
+	err := client.WithRetry(ctx, 3, func() error {
+		backend, target := m.api()
+		var err error
+		resp, err = backend.StopVM(ctx, target)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("%s", resp.Message)
+		}
+		m.watchOperation(ctx, backend, "stop", resp.OperationID)
+		return nil
+	})
+
+	if err != nil {
+		metrics.StopAttempts.Inc(vmName, "failed")
+		logger.Error("❌ Failed to stop VM",
+			"vm", vmName,
+			"error", err,
+		)
+		m.note("Остановка не удалась: %v", err)
+		m.notifier.Enqueue(notification.Notification{
+			VMName:   vmName,
+			Status:   types.StatusRunning,
+			Message:  fmt.Sprintf("⚠️ Не удалось остановить ВМ *%s*: %s", vmName, notification.EscapeMarkdown(err.Error())),
+			Priority: notification.PriorityNormal,
+		})
+		return fmt.Errorf("failed to stop VM: %w", err)
+	}
+
+	if resp.WasAlreadyStopped {
+		metrics.StopAttempts.Inc(vmName, "already_stopped")
+		logger.Info("ℹ️ VM was already stopped",
+			"vm", vmName,
+		)
+		return nil
+	}
+
+	metrics.StopAttempts.Inc(vmName, "stopped")
+	m.note("Остановка ВМ (%s)", trigger)
+	if trigger == stopScheduled {
+		m.notifier.Enqueue(notification.Notification{
+			VMName:   vmName,
+			Status:   types.StatusStopping,
+			Message:  fmt.Sprintf("⏹️ По расписанию: ВМ *%s* останавливается.", vmName),
+			Priority: notification.PriorityNormal,
+		})
+	}
+	return nil
+}
+
+// watchOperation follows the operation of an accepted power action in the
+// background. The API reports some failures, e.g. an exhausted quota, only
+// on the operation, so a failed one is noted on the incident and announced,
+// and the VM is checked again right away.
+func (m *VMMonitor) watchOperation(ctx context.Context, backend client.Backend, action, operationID string) {
+	watcher, ok := backend.(client.OperationWatcher)
+	if !ok || operationID == "" {
+		return
+	}
+
+	go func() {
+		opCtx, cancel := context.WithTimeout(ctx, operationTimeout)
+		defer cancel()
+
+		vmName := m.vm.Name
+		_, err := watcher.WaitOperation(opCtx, operationID, operationPollInterval)
+
+		var apiErr *client.APIError
+		switch {
+		case err == nil:
+			logger.Info("✅ VM operation completed",
+				"vm", vmName,
+				"action", action,
+				"operation_id", operationID,
+			)
+
+		case errors.As(err, &apiErr):
+			logger.Error("❌ VM operation failed",
+				"vm", vmName,
+				"action", action,
+				"operation_id", operationID,
+				"error", err,
+			)
+			m.note("Операция %s завершилась ошибкой: %s", operationNames[action], apiErr.Message)
+			m.TriggerCheck()
+			m.notifier.Enqueue(notification.Notification{
+				VMName:   vmName,
+				Status:   m.getCurrentStatus(),
+				Message:  fmt.Sprintf("❌ Операция %s ВМ *%s* завершилась ошибкой: %s", operationNames[action], vmName, notification.EscapeMarkdown(apiErr.Message)),
+				Priority: notification.PriorityCritical,
+				Incident: m.incidentID(),
+			})
+
+		case ctx.Err() == nil:
+			logger.Warn("⚠️ Failed to follow VM operation",
+				"vm", vmName,
+				"action", action,
+				"operation_id", operationID,
+				"error", err,
+			)
+		}
+	}()
+}
@@ -0,0 +1,177 @@
+package monitoring
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/client"
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+// hoursAround returns a schedule that is on now, or one that is off now
+func hoursAround(on bool) *config.PowerSchedule {
+	now := time.Now()
+	if on {
+		return &config.PowerSchedule{From: now.Add(-time.Hour).Format("15:04"), To: now.Add(time.Hour).Format("15:04")}
+	}
+	return &config.PowerSchedule{From: now.Add(time.Hour).Format("15:04"), To: now.Add(2 * time.Hour).Format("15:04")}
+}
+
+func TestVMMonitor_PowerSchedule(t *testing.T) {
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusRunning}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	m := NewVMMonitor(&config.VM{Name: "dev", Schedule: hoursAround(false)}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.SetPolicy(config.Policy{GracePeriod: time.Millisecond})
+	ctx := context.Background()
+
+	// Outside the schedule a running VM is stopped
+	m.check(ctx)
+	if backend.stopCount() != 1 {
+		t.Fatalf("expected one stop outside the schedule, got %d", backend.stopCount())
+	}
+
+	// ...and staying stopped is neither an incident nor a reason to start it
+	backend.setStatus(types.StatusStopped)
+	time.Sleep(2 * time.Millisecond)
+	m.check(ctx)
+	if got := m.State(); got.Status != types.StatusStopped || !got.IncidentStart.IsZero() {
+		t.Errorf("expected Stopped without an incident, got %s (incident %v)", got.Status, got.IncidentStart)
+	}
+	if backend.startCount() != 0 || backend.stopCount() != 1 {
+		t.Errorf("expected no further actions, got %d starts and %d stops", backend.startCount(), backend.stopCount())
+	}
+	if interval := m.getCurrentInterval(); interval != 60*time.Second {
+		t.Errorf("expected a relaxed interval while stopped by schedule, got %s", interval)
+	}
+
+	// When the schedule turns on, the VM is started
+	m.UpdateVM(config.VM{Name: "dev", Schedule: hoursAround(true)}, backend)
+	m.check(ctx)
+	if backend.startCount() != 1 {
+		t.Errorf("expected one scheduled start, got %d", backend.startCount())
+	}
+
+	queue.Stop()
+	for _, n := range sent.notifications() {
+		if strings.Contains(n.Message, "СБОЙ") {
+			t.Errorf("unexpected failure alert: %q", n.Message)
+		}
+	}
+	if msgs := sent.notifications(); len(msgs) != 2 ||
+		!strings.Contains(msgs[0].Message, "останавливается") || !strings.Contains(msgs[1].Message, "По расписанию") {
+		t.Errorf("expected scheduled stop and start notifications, got %+v", msgs)
+	}
+}
+
+func TestVMMonitor_DesiredState(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unmanaged", func(t *testing.T) {
+		var configMu sync.Mutex
+		backend := &fakeBackend{status: types.StatusRunning}
+		sent := &captureNotifier{}
+		queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+		queue.Start()
+
+		m := NewVMMonitor(&config.VM{Name: "obs", DesiredState: types.DesiredUnmanaged}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+		m.check(ctx)
+		backend.setStatus(types.StatusStopped)
+		m.check(ctx)
+		queue.Stop()
+
+		if backend.startCount() != 0 || backend.stopCount() != 0 {
+			t.Errorf("expected an observe-only VM to be left alone, got %d starts and %d stops", backend.startCount(), backend.stopCount())
+		}
+		if msgs := sent.notifications(); len(msgs) != 1 || !strings.Contains(msgs[0].Message, "СБОЙ") {
+			t.Errorf("expected a failure alert, got %+v", msgs)
+		}
+		if m.State().IncidentStart.IsZero() {
+			t.Error("expected the failure to open an incident")
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		var configMu sync.Mutex
+		backend := &fakeBackend{status: types.StatusStopped}
+		sent := &captureNotifier{}
+		queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+		queue.Start()
+
+		m := NewVMMonitor(&config.VM{Name: "cold", DesiredState: types.DesiredStopped}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+		m.check(ctx)
+		if backend.startCount() != 0 || len(sent.notifications()) != 0 {
+			t.Errorf("expected a stopped VM that should be stopped to be left alone")
+		}
+
+		// Someone started it: alert on the drift and stop it again
+		backend.setStatus(types.StatusRunning)
+		m.check(ctx)
+		queue.Stop()
+
+		if backend.stopCount() != 1 {
+			t.Errorf("expected one stop, got %d", backend.stopCount())
+		}
+		msgs := sent.notifications()
+		if len(msgs) != 1 || !strings.Contains(msgs[0].Message, "РАСХОЖДЕНИЕ") {
+			t.Errorf("expected a drift alert and no recovery message, got %+v", msgs)
+		}
+	})
+}
+
+// failingOperationBackend accepts starts, but their operation fails
+type failingOperationBackend struct {
+	fakeBackend
+}
+
+func (b *failingOperationBackend) StartVM(ctx context.Context, target string) (*client.StartVMResponse, error) {
+	b.fakeBackend.StartVM(ctx, target)
+	return &client.StartVMResponse{Success: true, OperationID: "op-1"}, nil
+}
+
+func (b *failingOperationBackend) WaitOperation(ctx context.Context, operationID string, interval time.Duration) (*client.Operation, error) {
+	apiErr := &client.APIError{HTTPStatus: 200, Code: 8, Message: "Quota limit vpc.externalAddresses.count exceeded"}
+	return &client.Operation{ID: operationID, Done: true, Error: apiErr}, apiErr
+}
+
+func TestVMMonitor_ReportsFailedOperation(t *testing.T) {
+	var configMu sync.Mutex
+	backend := &failingOperationBackend{fakeBackend{status: types.StatusStopped}}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	m := NewVMMonitor(&config.VM{Name: "vm-1"}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	if err := m.StartNow(context.Background()); err != nil {
+		t.Fatalf("expected the start request to be accepted, got %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		failed := false
+		for _, n := range sent.notifications() {
+			failed = failed || strings.Contains(n.Message, "Quota limit")
+		}
+		if failed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the failed operation to be announced, got %+v", sent.notifications())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	queue.Stop()
+
+	// The grace period of the start no longer holds back the next check
+	if got := m.State(); time.Now().Before(got.GracePeriodUntil) {
+		t.Errorf("expected the grace period to end, it runs until %s", got.GracePeriodUntil)
+	}
+}
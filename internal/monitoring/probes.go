@@ -0,0 +1,230 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/probe"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// vmProbes returns the parsed probes of the VM, parsing them once per configuration,
+// and how many of them must pass
+func (m *VMMonitor) vmProbes() ([]*probe.Probe, probe.Requirement) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+
+	// Validated when the configuration was loaded
+	required, _ := probe.ParseRequirement(m.vm.ProbesRequired)
+	if m.probes != nil || len(m.vm.Probes) == 0 {
+		return m.probes, required
+	}
+
+	for _, cfg := range m.vm.Probes {
+		p, err := cfg.Probe()
+		if err != nil {
+			logger.Error("Invalid probe",
+				"vm", m.vm.Name,
+				"error", err,
+			)
+			continue
+		}
+		m.probes = append(m.probes, p)
+	}
+	return m.probes, required
+}
+
+// checkProbes runs the VM's probes once the VM is known to be running.
+// A probe is down after failing FailureThreshold runs in a row, and the VM is
+// healthy while enough probes are not down.
+func (m *VMMonitor) checkProbes(ctx context.Context) {
+	probes, required := m.vmProbes()
+	if len(probes) == 0 {
+		return
+	}
+
+	results := probe.CheckAll(ctx, probes, m.ip())
+	if ctx.Err() != nil {
+		return
+	}
+
+	var passed int
+	var failures, pending []string
+
+	m.mu.Lock()
+	if m.probeFailures == nil {
+		m.probeFailures = make(map[string]int)
+	}
+	for i, r := range results {
+		metrics.VMProbeLatency.Set(r.Latency.Seconds(), m.vm.Name, r.Probe)
+		if r.OK {
+			metrics.Probes.Inc(m.vm.Name, r.Probe, "success")
+			delete(m.probeFailures, r.Probe)
+			passed++
+			continue
+		}
+
+		metrics.Probes.Inc(m.vm.Name, r.Probe, "failure")
+		m.probeFailures[r.Probe]++
+		failed, threshold := m.probeFailures[r.Probe], probes[i].FailureThreshold()
+		if failed < threshold {
+			// Not down yet: a single failure may be the network
+			pending = append(pending, fmt.Sprintf("%s: %v (%d/%d)", r.Probe, r.Err, failed, threshold))
+			passed++
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", r.Probe, r.Err))
+	}
+	m.mu.Unlock()
+
+	if len(pending) > 0 {
+		logger.Warn("🩺 Probe failed, waiting for confirmation",
+			"vm", m.vm.Name,
+			"errors", strings.Join(pending, "; "),
+		)
+	}
+
+	if required.Met(passed, len(results)) {
+		metrics.VMProbeHealthy.Set(1, m.vm.Name)
+		if len(failures) > 0 {
+			logger.Warn("🩺 Probes down, but enough still pass",
+				"vm", m.vm.Name,
+				"passed", fmt.Sprintf("%d/%d", passed, len(results)),
+				"required", required,
+				"errors", strings.Join(failures, "; "),
+			)
+		} else if len(pending) == 0 {
+			logger.Info("🩺 Probes OK",
+				"vm", m.vm.Name,
+				"probes", len(results),
+			)
+		}
+		m.setAppHealthy(passed, len(results))
+		return
+	}
+
+	metrics.VMProbeHealthy.Set(0, m.vm.Name)
+	logger.Warn("🩺 Probes failed",
+		"vm", m.vm.Name,
+		"passed", fmt.Sprintf("%d/%d", passed, len(results)),
+		"required", required,
+		"errors", strings.Join(failures, "; "),
+	)
+
+	// Probe names and errors are not Markdown
+	detail := "• " + notification.EscapeMarkdown(strings.Join(failures, "\n• "))
+	if required != probe.RequireAll {
+		detail += fmt.Sprintf("\n\nПрошло %d из %d, требуется: %s", passed, len(results), required)
+	}
+	m.setAppDown(strings.Join(failures, "; "), detail)
+	m.remediate(ctx)
+}
+
+// isSuspect reports whether a running VM needs a closer look: its application
+// is down, or pings or probes fail without having reached their thresholds yet
+func (m *VMMonitor) isSuspect() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.appDown || m.pingFailures > 0 || len(m.probeFailures) > 0
+}
+
+// isAppDown reports whether the VM runs but its application does not
+func (m *VMMonitor) isAppDown() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.appDown
+}
+
+// setAppDown records failing probes and alerts once per application failure.
+// A failure that starts during maintenance is alerted once maintenance ends.
+func (m *VMMonitor) setAppDown(failure, detail string) {
+	m.mu.Lock()
+	if !m.appDown {
+		m.appDown = true
+		m.remedy = remedyWaiting
+	}
+	m.appFailure = failure
+	alert := !m.appAlerted && !m.expectsDownLocked()
+	if alert {
+		m.appAlerted = true
+		// The remediation ladder starts with the alert
+		m.appDownSince = time.Now()
+		m.openIncidentLocked(m.appDownSince)
+		m.noteLocked("Проверки не проходят: %s", failure)
+	}
+	incidentID := ""
+	if m.incident != nil {
+		incidentID = m.incident.ID
+	}
+	m.mu.Unlock()
+
+	if !alert {
+		return
+	}
+	m.persist()
+
+	message := fmt.Sprintf("🩺 СБОЙ ПРИЛОЖЕНИЯ: ВМ *%s* работает, но проверки не проходят.\n\n%s\n\nИнцидент: `%s`", m.vm.Name, detail, incidentID)
+	m.notify(notification.Notification{
+		VMName:   m.vm.Name,
+		Status:   types.StatusRunning,
+		Message:  message,
+		Priority: notification.PriorityCritical,
+		Keyboard: notification.NewAlertKeyboard(m.vm.Name),
+		Incident: incidentID,
+	})
+}
+
+// setAppHealthy closes an application failure once enough probes pass again
+func (m *VMMonitor) setAppHealthy(passed, total int) {
+	m.mu.Lock()
+	was, alerted, restarted := m.appDown, m.appAlerted, !m.restartedAt.IsZero()
+	m.resetAppLocked()
+	var resolved *Incident
+	if alerted {
+		m.noteLocked("Проверки снова проходят")
+		resolved = m.resolveIncidentLocked(time.Now())
+	}
+	m.mu.Unlock()
+
+	if !was {
+		return
+	}
+	m.persist()
+
+	logger.Info("✅ Application recovered",
+		"vm", m.vm.Name,
+		"after_restart", restarted,
+	)
+	if !alerted {
+		return
+	}
+
+	message := fmt.Sprintf("✅ ВОССТАНОВЛЕНИЕ: приложение на ВМ *%s* снова отвечает", m.vm.Name)
+	if restarted {
+		message += " после перезапуска"
+	}
+	message += fmt.Sprintf(".\n\nПроверки: %d из %d OK", passed, total) + recoveryDetails(resolved)
+	m.notify(notification.Notification{
+		VMName:   m.vm.Name,
+		Status:   types.StatusRunning,
+		Message:  message,
+		Priority: notification.PriorityCritical,
+		Incident: incidentKey(resolved),
+		Resolve:  true,
+	})
+}
+
+// resetAppLocked forgets the application failure and its remediation
+func (m *VMMonitor) resetAppLocked() {
+	m.appDown = false
+	m.appAlerted = false
+	m.appFailure = ""
+	m.appDownSince = time.Time{}
+	m.remedy = remedyWaiting
+	m.restartedAt = time.Time{}
+}
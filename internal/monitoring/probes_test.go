@@ -0,0 +1,180 @@
+package monitoring
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestVMMonitor_Probes(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusRunning}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	vm := &config.VM{Name: "vm-1", Probes: []config.Probe{{Name: "web", HTTP: server.URL}}}
+	m := NewVMMonitor(vm, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+
+	// The VM runs, but its application does not answer
+	m.check(context.Background())
+	got := m.State()
+	if got.Status != types.StatusRunning || !got.AppDown || got.IncidentStart.IsZero() {
+		t.Fatalf("expected a Running VM with a failing application and an incident, got %+v", got)
+	}
+	if !strings.Contains(got.AppFailure, "web: status 503") {
+		t.Errorf("unexpected failure detail %q", got.AppFailure)
+	}
+	if backend.startCount() != 0 {
+		t.Errorf("expected no start for a running VM, got %d", backend.startCount())
+	}
+	if interval := m.getCurrentInterval(); interval != 5*time.Second {
+		t.Errorf("expected the minimum interval while the application is down, got %s", interval)
+	}
+
+	// Still failing: no second alert
+	m.check(context.Background())
+
+	healthy.Store(true)
+	m.check(context.Background())
+	if got := m.State(); got.AppDown || !got.IncidentStart.IsZero() {
+		t.Errorf("expected the application failure to be resolved, got %+v", got)
+	}
+
+	queue.Stop()
+	var alerts, recoveries int
+	for _, n := range sent.notifications() {
+		switch {
+		case strings.Contains(n.Message, "СБОЙ ПРИЛОЖЕНИЯ"):
+			alerts++
+		case n.Resolve:
+			recoveries++
+		}
+	}
+	if alerts != 1 || recoveries != 1 {
+		t.Errorf("expected one alert and one recovery, got %d and %d", alerts, recoveries)
+	}
+}
+
+func TestVMMonitor_ProbeThresholds(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusRunning}
+	queue := notification.NewNotificationQueue(1)
+
+	vm := &config.VM{Name: "vm-1", ProbesRequired: "any", Probes: []config.Probe{
+		{Name: "web", HTTP: broken.URL, FailureThreshold: 2},
+		{Name: "port", TCP: ln.Addr().String()},
+	}}
+	m := NewVMMonitor(vm, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+
+	// One of two is enough
+	m.check(context.Background())
+	m.check(context.Background())
+	if m.State().AppDown {
+		t.Fatal("expected the VM to stay healthy while any probe passes")
+	}
+
+	// Requiring all: the failing probe counts only once it reaches its threshold
+	vm2 := *vm
+	vm2.ProbesRequired = "all"
+	m.UpdateVM(vm2, backend)
+	m.check(context.Background())
+	if m.State().AppDown {
+		t.Fatal("expected a single failure to stay below the threshold")
+	}
+	if !m.isSuspect() {
+		t.Error("expected a pending failure to make the VM suspect")
+	}
+	m.check(context.Background())
+	if got := m.State(); !got.AppDown || !strings.Contains(got.AppFailure, "web") {
+		t.Errorf("expected the application to be down after two failures, got %+v", got)
+	}
+}
+
+func TestVMMonitor_Remediation(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusRunning}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	vm := &config.VM{Name: "vm-1", Probes: []config.Probe{{Name: "web", HTTP: server.URL}}}
+	m := NewVMMonitor(vm, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.SetPolicy(config.Policy{
+		GracePeriod: time.Nanosecond,
+		Remediation: config.Remediation{RestartAfter: time.Nanosecond, VerifyAfter: time.Nanosecond},
+	})
+
+	// Alert, then restart right away
+	m.check(context.Background())
+	if backend.restartCount() != 1 || m.State().RestartedAt.IsZero() {
+		t.Fatalf("expected one restart, got %d", backend.restartCount())
+	}
+
+	// Still down after the restart: give up and ask for help, once
+	m.check(context.Background())
+	m.check(context.Background())
+	if got := m.State(); !got.RemedyExhausted || backend.restartCount() != 1 {
+		t.Errorf("expected the ladder to be exhausted after one restart, got %+v with %d restarts", got, backend.restartCount())
+	}
+
+	healthy.Store(true)
+	m.check(context.Background())
+	if got := m.State(); got.AppDown || got.RemedyExhausted || !got.IncidentStart.IsZero() {
+		t.Errorf("expected recovery to reset the ladder, got %+v", got)
+	}
+
+	queue.Stop()
+	var steps []string
+	for _, n := range sent.notifications() {
+		switch {
+		case strings.Contains(n.Message, "СБОЙ ПРИЛОЖЕНИЯ"):
+			steps = append(steps, "alert")
+		case strings.Contains(n.Message, "Перезапускаю"):
+			steps = append(steps, "restart")
+		case strings.Contains(n.Message, "НЕ ПОМОГ"):
+			steps = append(steps, "did not help")
+		case strings.Contains(n.Message, "после перезапуска"):
+			steps = append(steps, "recovered")
+		}
+	}
+	if got := strings.Join(steps, ", "); got != "alert, restart, did not help, recovered" {
+		t.Errorf("unexpected notifications: %s", got)
+	}
+}
@@ -0,0 +1,296 @@
+package monitoring
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/health"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+const (
+	// configPath is the VM configuration the coordinator reads and writes back
+	configPath = "vms.yaml"
+	// configPollInterval is how often vms.yaml is checked for changes
+	configPollInterval = 10 * time.Second
+)
+
+// SetConfigHealth reports the outcome of every configuration reload on state
+func (c *Coordinator) SetConfigHealth(state *health.State) {
+	c.configHealth = state
+}
+
+// RequestReload asks the coordinator to re-read vms.yaml (e.g. on SIGHUP)
+func (c *Coordinator) RequestReload() {
+	select {
+	case c.reloadChan <- struct{}{}:
+	default:
+		// A reload is already pending
+	}
+}
+
+// configWatcher reloads vms.yaml when its content changes or a reload is requested
+func (c *Coordinator) configWatcher(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.configChanged() {
+				logger.Info("📝 vms.yaml changed, reloading")
+				c.reload(ctx)
+			}
+		case <-c.reloadChan:
+			logger.Info("📝 Reload requested, re-reading vms.yaml")
+			c.reload(ctx)
+		}
+	}
+}
+
+// configChanged reports whether vms.yaml differs from what was last loaded or saved
+func (c *Coordinator) configChanged() bool {
+	hash, err := fileHash(configPath)
+	if err != nil {
+		return false
+	}
+
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	return hash != c.configHash
+}
+
+// reload re-reads the configuration and reconciles the monitors of vms.yaml.
+// Only the vms section is applied; other settings still need a restart.
+func (c *Coordinator) reload(ctx context.Context) {
+	hash, _ := fileHash(configPath)
+
+	next, err := config.Load(configPath)
+
+	// Remember the content even if it is invalid so it is not retried every poll
+	c.configMu.Lock()
+	c.configHash = hash
+	c.configMu.Unlock()
+
+	if err != nil {
+		logger.Error("❌ Failed to reload configuration, keeping the current one",
+			"error", err,
+		)
+		c.setConfigHealth(err)
+		c.notifyConfig(configPath, notification.PriorityNormal,
+			fmt.Sprintf("⚠️ Не удалось перечитать %s, действует прежняя конфигурация:\n\n%s", configPath, notification.EscapeMarkdown(err.Error())))
+		return
+	}
+
+	c.setConfigHealth(nil)
+	c.applyPolicy(next.Policy)
+	c.applyVMs(ctx, next.VMs)
+}
+
+// applyPolicy replaces the global policy of all monitors
+func (c *Coordinator) applyPolicy(policy config.Policy) {
+	c.configMu.Lock()
+	changed := !reflect.DeepEqual(c.config.Policy, policy)
+	c.config.Policy = policy
+	c.configMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	logger.Info("✏️ Global policy changed, updating monitors")
+	c.monitorsMu.RLock()
+	defer c.monitorsMu.RUnlock()
+	for _, m := range c.monitors {
+		m.SetPolicy(policy)
+	}
+}
+
+// applyVMs reconciles running monitors with a new list of configured VMs
+func (c *Coordinator) applyVMs(ctx context.Context, vms []config.VM) {
+	wanted := make(map[string]bool, len(vms))
+	for _, vm := range vms {
+		wanted[vm.Name] = true
+	}
+
+	// Existing VMs keep their monitor (and state); changed settings are applied in place
+	var added []string
+	for _, vm := range vms {
+		m := c.staticMonitor(vm.Name)
+		if m == nil {
+			added = append(added, vm.Name)
+			continue
+		}
+
+		// An empty IP in the file means "keep the discovered one"
+		current := m.VM()
+		if vm.IP == "" {
+			current.IP = ""
+		}
+		if reflect.DeepEqual(current, vm) {
+			continue
+		}
+
+		logger.Info("✏️ VM settings changed, updating monitor",
+			"vm", vm.Name,
+		)
+		m.UpdateVM(vm, c.backendFor(&vm))
+		c.notifyConfig(vm.Name, notification.PriorityLow,
+			fmt.Sprintf("✏️ Настройки ВМ *%s* обновлены из %s.", vm.Name, configPath))
+	}
+
+	monitors := c.staticMonitors()
+
+	c.configMu.Lock()
+	var removed []string
+	for _, vm := range c.config.VMs {
+		if !wanted[vm.Name] {
+			removed = append(removed, vm.Name)
+		}
+	}
+	c.config.VMs = vms
+	c.syncStaticVMs(monitors)
+	c.configMu.Unlock()
+
+	for _, name := range removed {
+		logger.Info("➖ VM removed from configuration, stopping monitor",
+			"vm", name,
+		)
+		c.stopMonitor(name)
+		c.notifyConfig(name, notification.PriorityLow,
+			fmt.Sprintf("➖ ВМ *%s* удалена из %s, мониторинг остановлен.", name, configPath))
+	}
+
+	for _, name := range added {
+		// A VM listed in vms.yaml takes over from a discovered one of the same name
+		c.forgetDiscovered(name)
+
+		vm := c.staticVM(name)
+		if vm == nil {
+			continue
+		}
+
+		logger.Info("➕ VM added to configuration, starting monitor",
+			"vm", name,
+		)
+		c.startMonitor(ctx, vm, c.ipUpdateChan)
+		c.notifyConfig(name, notification.PriorityLow,
+			fmt.Sprintf("➕ ВМ *%s* добавлена в %s, мониторинг запущен.", name, configPath))
+	}
+
+	logger.Info("✅ Configuration reloaded",
+		"vm_count", len(vms),
+		"added", len(added),
+		"removed", len(removed),
+	)
+}
+
+// staticMonitor returns the monitor of a VM from vms.yaml (not a discovered one)
+func (c *Coordinator) staticMonitor(name string) *VMMonitor {
+	c.monitorsMu.RLock()
+	for _, discovered := range c.discovered {
+		if discovered == name {
+			c.monitorsMu.RUnlock()
+			return nil
+		}
+	}
+	c.monitorsMu.RUnlock()
+
+	return c.findMonitor(name)
+}
+
+// staticVM returns the entry of c.config.VMs with the given name
+func (c *Coordinator) staticVM(name string) *config.VM {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+
+	for i := range c.config.VMs {
+		if c.config.VMs[i].Name == name {
+			return &c.config.VMs[i]
+		}
+	}
+	return nil
+}
+
+// forgetDiscovered stops the discovered monitor with the given name, if any
+func (c *Coordinator) forgetDiscovered(name string) {
+	c.monitorsMu.Lock()
+	found := false
+	for id, discovered := range c.discovered {
+		if discovered == name {
+			delete(c.discovered, id)
+			found = true
+		}
+	}
+	c.monitorsMu.Unlock()
+
+	if found {
+		c.stopMonitor(name)
+	}
+}
+
+// staticMonitors returns the monitors of VMs from vms.yaml by name
+func (c *Coordinator) staticMonitors() map[string]*VMMonitor {
+	c.monitorsMu.RLock()
+	defer c.monitorsMu.RUnlock()
+
+	byName := make(map[string]*VMMonitor, len(c.monitors))
+	for _, m := range c.monitors {
+		byName[m.Name()] = m
+	}
+	for _, name := range c.discovered {
+		delete(byName, name)
+	}
+	return byName
+}
+
+// syncStaticVMs copies settings learned by monitors (e.g. new IPs) into
+// c.config.VMs so they are saved. configMu must be held, monitorsMu must not.
+func (c *Coordinator) syncStaticVMs(monitors map[string]*VMMonitor) {
+	for i := range c.config.VMs {
+		if m, ok := monitors[c.config.VMs[i].Name]; ok && m.vm != &c.config.VMs[i] {
+			ip := c.config.VMs[i].IP
+			c.config.VMs[i] = *m.vm
+			if c.config.VMs[i].IP == "" {
+				c.config.VMs[i].IP = ip
+			}
+		}
+	}
+}
+
+func (c *Coordinator) setConfigHealth(err error) {
+	if c.configHealth != nil {
+		c.configHealth.Set(err)
+	}
+}
+
+func (c *Coordinator) notifyConfig(vmName string, priority notification.Priority, message string) {
+	c.notifier.Enqueue(notification.Notification{
+		VMName:   vmName,
+		Status:   types.StatusUnknown,
+		Message:  message,
+		Priority: priority,
+	})
+}
+
+// fileHash returns the SHA-256 of a file's content
+func fileHash(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
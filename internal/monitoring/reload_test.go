@@ -0,0 +1,65 @@
+package monitoring
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/client"
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+)
+
+func TestCoordinator_ApplyVMs(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"RUNNING"}`))
+	}))
+	defer gateway.Close()
+
+	cfg := &config.Config{
+		MinCheckInterval: time.Second,
+		MaxCheckInterval: time.Minute,
+		VMs: []config.VM{
+			{Name: "kept", URL: gateway.URL + "/kept"},
+			{Name: "removed", URL: gateway.URL + "/removed"},
+		},
+	}
+	c := NewCoordinator(cfg, client.NewYandexClient(), nil, notification.NewNotificationQueue(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.Wait()
+	}()
+
+	for i := range cfg.VMs {
+		c.startMonitor(ctx, &cfg.VMs[i], c.ipUpdateChan)
+	}
+	kept := c.findMonitor("kept")
+
+	c.applyVMs(ctx, []config.VM{
+		{Name: "kept", URL: gateway.URL + "/kept-v2", IP: "10.0.0.5"},
+		{Name: "added", URL: gateway.URL + "/added"},
+	})
+
+	if got := c.findMonitor("kept"); got != kept {
+		t.Error("expected the monitor of an existing VM to be kept")
+	}
+	if vm := kept.VM(); vm.URL != gateway.URL+"/kept-v2" || vm.IP != "10.0.0.5" {
+		t.Errorf("expected updated settings to be applied, got %+v", vm)
+	}
+	if c.findMonitor("removed") != nil {
+		t.Error("expected the monitor of a removed VM to be stopped")
+	}
+	if c.findMonitor("added") == nil {
+		t.Error("expected a monitor for the added VM")
+	}
+
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	if len(c.config.VMs) != 2 || c.config.VMs[0].URL != gateway.URL+"/kept-v2" || c.config.VMs[1].Name != "added" {
+		t.Errorf("unexpected configuration after reload: %+v", c.config.VMs)
+	}
+}
@@ -0,0 +1,166 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/client"
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// remedyStage is how far the remediation ladder got for the current application failure
+type remedyStage int
+
+const (
+	// remedyWaiting: alerted, waiting restart_after before restarting the VM
+	remedyWaiting remedyStage = iota
+	// remedyRestarted: restarted, waiting verify_after for the application to recover
+	remedyRestarted
+	// remedyExhausted: the restart failed or did not help, an operator has to step in
+	remedyExhausted
+)
+
+// remediate moves an alerted application failure up the ladder:
+// alert → wait restart_after → restart the VM → wait verify_after → alert again
+func (m *VMMonitor) remediate(ctx context.Context) {
+	restartAfter, verifyAfter := m.policy().RemediationSteps()
+
+	m.mu.RLock()
+	alerted, stage := m.appAlerted, m.remedy
+	downSince, restartedAt, failure := m.appDownSince, m.restartedAt, m.appFailure
+	m.mu.RUnlock()
+
+	if !alerted || m.expectsDown() {
+		return
+	}
+	if m.desiredState() == types.DesiredUnmanaged {
+		// Observe only: the alert is all there is
+		return
+	}
+
+	now := time.Now()
+	switch stage {
+	case remedyWaiting:
+		if restartAfter == 0 || now.Sub(downSince) < restartAfter {
+			return
+		}
+		m.restartVM(ctx, now.Sub(downSince))
+
+	case remedyRestarted:
+		if now.Sub(restartedAt) < verifyAfter {
+			return
+		}
+		m.mu.Lock()
+		m.remedy = remedyExhausted
+		m.noteLocked("Перезапуск не помог")
+		m.mu.Unlock()
+
+		logger.Error("❌ Restart did not help",
+			"vm", m.vm.Name,
+			"restarted_at", restartedAt.Format(time.RFC3339),
+			"errors", failure,
+		)
+		message := fmt.Sprintf("❌ ПЕРЕЗАПУСК НЕ ПОМОГ: приложение на ВМ *%s* не отвечает %s после перезапуска.\n\n%s\n\nНужно вмешательство.",
+			m.vm.Name, now.Sub(restartedAt).Round(time.Second), notification.EscapeMarkdown(failure))
+		m.notifier.Enqueue(notification.Notification{
+			VMName:   m.vm.Name,
+			Status:   types.StatusRunning,
+			Message:  message,
+			Priority: notification.PriorityCritical,
+			Keyboard: notification.NewAlertKeyboard(m.vm.Name),
+			Incident: m.incidentID(),
+		})
+	}
+}
+
+// restartVM restarts a VM whose application has been down for downFor
+func (m *VMMonitor) restartVM(ctx context.Context, downFor time.Duration) {
+	vmName := m.vm.Name
+	logger.Info("🔄 Restarting VM, application is down",
+		"vm", vmName,
+		"down_for", downFor.Round(time.Second),
+	)
+
+	if err := m.requestRestart(ctx); err != nil {
+		m.mu.Lock()
+		m.remedy = remedyExhausted
+		m.mu.Unlock()
+
+		m.notifier.Enqueue(notification.Notification{
+			VMName:   vmName,
+			Status:   types.StatusRunning,
+			Message:  fmt.Sprintf("⚠️ Не удалось перезапустить ВМ *%s*: %s\n\nНужно вмешательство.", vmName, notification.EscapeMarkdown(err.Error())),
+			Priority: notification.PriorityCritical,
+			Keyboard: notification.NewAlertKeyboard(vmName),
+			Incident: m.incidentID(),
+		})
+		return
+	}
+
+	m.mu.Lock()
+	m.remedy = remedyRestarted
+	m.restartedAt = time.Now()
+	m.mu.Unlock()
+
+	m.notifier.Enqueue(notification.Notification{
+		VMName:   vmName,
+		Status:   types.StatusRestarting,
+		Message:  fmt.Sprintf("🔄 Перезапуск: приложение на ВМ *%s* не отвечает %s. Перезапускаю ВМ.", vmName, downFor.Round(time.Second)),
+		Priority: notification.PriorityNormal,
+		Incident: m.incidentID(),
+	})
+}
+
+// requestRestart restarts the VM through its backend and waits out the boot
+// with a grace period
+func (m *VMMonitor) requestRestart(ctx context.Context) error {
+	vmName := m.vm.Name
+
+	var (
+		backend client.Backend
+		resp    *client.RestartVMResponse
+	)
+	err := client.WithRetry(ctx, 3, func() error {
+		var target string
+		backend, target = m.api()
+		var err error
+		resp, err = backend.RestartVM(ctx, target)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("%s", resp.Message)
+		}
+		return nil
+	})
+
+	if err != nil {
+		metrics.RestartAttempts.Inc(vmName, "failed")
+		logger.Error("❌ Failed to restart VM",
+			"vm", vmName,
+			"error", err,
+		)
+		m.note("Перезапуск не удался: %v", err)
+		return err
+	}
+
+	metrics.RestartAttempts.Inc(vmName, "restarted")
+	grace := m.policy().Grace()
+	m.mu.Lock()
+	m.powerActionAt = time.Now()
+	m.gracePeriodUntil = m.powerActionAt.Add(grace)
+	m.noteLocked("Перезапуск ВМ")
+	m.mu.Unlock()
+	m.persist()
+	m.watchOperation(ctx, backend, "restart", resp.OperationID)
+
+	logger.Info("✅ VM restart requested",
+		"vm", vmName,
+		"operation_id", resp.OperationID,
+	)
+	return nil
+}
@@ -67,8 +67,14 @@ func TestVMStatus_ShouldStartVM(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(string(tt.status), func(t *testing.T) {
-			if got := tt.status.ShouldStartVM(); got != tt.expected {
-				t.Errorf("ShouldStartVM() = %v, want %v", got, tt.expected)
+			if got := tt.status.ShouldStartVM(types.DesiredRunning); got != tt.expected {
+				t.Errorf("ShouldStartVM(running) = %v, want %v", got, tt.expected)
+			}
+			// Only VMs that should be running are ever started
+			for _, desired := range []types.DesiredState{types.DesiredStopped, types.DesiredUnmanaged} {
+				if tt.status.ShouldStartVM(desired) {
+					t.Errorf("ShouldStartVM(%s) = true, want false", desired)
+				}
 			}
 		})
 	}
@@ -0,0 +1,233 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// checkStuckStatus handles a VM that stays in a transitional status past its
+// timeout. It alerts and runs the configured action once per stuck status,
+// and reports once more if the action did not help.
+func (m *VMMonitor) checkStuckStatus(ctx context.Context) {
+	status := m.getCurrentStatus()
+
+	if !status.IsTransitional() || m.inMaintenanceNow() {
+		return
+	}
+
+	timeout := m.policy().Timeout(status)
+	if timeout == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	enteredAt := m.lastStatusTime
+	stuckFor := time.Since(enteredAt)
+	if stuckFor <= timeout {
+		m.mu.Unlock()
+		return
+	}
+	handled := m.stuckSince.Equal(enteredAt)
+	action, actedAt, reported := m.stuckAction, m.stuckActedAt, m.stuckReported
+	if !handled {
+		m.stuckSince = enteredAt
+		m.stuckAction = ""
+		m.stuckReported = false
+	}
+	m.mu.Unlock()
+
+	if handled {
+		if action != "" && !reported && time.Since(actedAt) > timeout {
+			m.reportStuckAction(status, action, stuckFor)
+		}
+		return
+	}
+
+	action = m.stuckActionFor(status)
+	logger.Warn("⏰ VM stuck in transitional status",
+		"vm", m.vm.Name,
+		"status", status,
+		"duration", stuckFor,
+		"action", action,
+	)
+
+	m.note("Застряла в статусе %s, действие: %s", status, describeStuckAction(action))
+	message := fmt.Sprintf("⚠️ ВНИМАНИЕ: ВМ *%s* застряла в статусе %s более %v",
+		m.vm.Name, status, stuckFor.Round(time.Second))
+	if action != config.StuckAlert {
+		message += fmt.Sprintf("\n\nДействие: %s", describeStuckAction(action))
+	}
+	m.notifier.Enqueue(notification.Notification{
+		VMName:   m.vm.Name,
+		Status:   status,
+		Message:  message,
+		Priority: notification.PriorityNormal,
+		Incident: m.incidentID(),
+	})
+
+	if action == config.StuckAlert {
+		return
+	}
+
+	m.markPowerAction()
+	if err := m.runStuckAction(ctx, action); err != nil {
+		logger.Error("❌ Stuck action failed",
+			"vm", m.vm.Name,
+			"action", action,
+			"error", err,
+		)
+		if action == config.StuckStart || action == config.StuckRestart {
+			// stopVM reports its own failures
+			m.notifier.Enqueue(notification.Notification{
+				VMName:   m.vm.Name,
+				Status:   status,
+				Message:  fmt.Sprintf("❌ Не удалось выполнить действие «%s» для ВМ *%s*: %s", describeStuckAction(action), m.vm.Name, notification.EscapeMarkdown(err.Error())),
+				Priority: notification.PriorityCritical,
+				Keyboard: notification.NewAlertKeyboard(m.vm.Name),
+				Incident: m.incidentID(),
+			})
+		}
+		return
+	}
+
+	m.mu.Lock()
+	m.stuckAction = action
+	m.stuckActedAt = time.Now()
+	m.mu.Unlock()
+}
+
+// stuckActionFor returns the configured action for a VM stuck in status,
+// limited to what its desired state allows
+func (m *VMMonitor) stuckActionFor(status types.VMStatus) config.StuckAction {
+	action := m.policy().StuckAction(status)
+	if action == config.StuckAlert {
+		return action
+	}
+
+	switch {
+	case m.desiredState() == types.DesiredUnmanaged:
+		return config.StuckAlert
+	case m.expectsDown() && action == config.StuckStopStart:
+		return config.StuckStop
+	case m.expectsDown() && action != config.StuckStop:
+		return config.StuckAlert
+	}
+	return action
+}
+
+// runStuckAction requests the action from the backend without waiting for it
+func (m *VMMonitor) runStuckAction(ctx context.Context, action config.StuckAction) error {
+	switch action {
+	case config.StuckStart:
+		return m.startVM(ctx, startStuck)
+	case config.StuckStop:
+		return m.stopVM(ctx, stopStuck)
+	case config.StuckStopStart:
+		if err := m.stopVM(ctx, stopStuck); err != nil {
+			return err
+		}
+		m.mu.Lock()
+		m.startWhenStopped = true
+		m.mu.Unlock()
+		return nil
+	case config.StuckRestart:
+		return m.requestRestart(ctx)
+	}
+	return nil
+}
+
+// reportStuckAction tells once that an action did not get the VM out of status
+func (m *VMMonitor) reportStuckAction(status types.VMStatus, action config.StuckAction, stuckFor time.Duration) {
+	m.mu.Lock()
+	m.stuckReported = true
+	m.mu.Unlock()
+
+	logger.Error("❌ Stuck action did not help",
+		"vm", m.vm.Name,
+		"status", status,
+		"action", action,
+		"duration", stuckFor,
+	)
+	m.notifier.Enqueue(notification.Notification{
+		VMName:   m.vm.Name,
+		Status:   status,
+		Message:  fmt.Sprintf("❌ Действие «%s» не помогло: ВМ *%s* всё ещё в статусе %s (%v). Нужно вмешательство.", describeStuckAction(action), m.vm.Name, status, stuckFor.Round(time.Second)),
+		Priority: notification.PriorityCritical,
+		Keyboard: notification.NewAlertKeyboard(m.vm.Name),
+		Incident: m.incidentID(),
+	})
+}
+
+// takeStartWhenStopped reports whether a stop_start action is waiting for the
+// VM to stop, and clears it
+func (m *VMMonitor) takeStartWhenStopped() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	start := m.startWhenStopped
+	m.startWhenStopped = false
+	return start
+}
+
+// resolveStuck reports the outcome of a stuck action once the VM leaves the
+// transitional statuses
+func (m *VMMonitor) resolveStuck(status types.VMStatus) {
+	if status.IsTransitional() {
+		return
+	}
+
+	m.mu.Lock()
+	if m.startWhenStopped && status == types.StatusStopped {
+		// stop_start is halfway: the start follows
+		m.mu.Unlock()
+		return
+	}
+	action := m.stuckAction
+	m.stuckAction = ""
+	m.startWhenStopped = false
+	m.mu.Unlock()
+
+	if action == "" {
+		return
+	}
+
+	logger.Info("✅ VM left the stuck status",
+		"vm", m.vm.Name,
+		"action", action,
+		"status", status,
+	)
+
+	var message string
+	if status == types.StatusRunning {
+		message = fmt.Sprintf("✅ После действия «%s» ВМ *%s* снова работает.", describeStuckAction(action), m.vm.Name)
+	} else {
+		message = fmt.Sprintf("ℹ️ После действия «%s» ВМ *%s* в статусе %s.", describeStuckAction(action), m.vm.Name, status)
+	}
+	m.notifier.Enqueue(notification.Notification{
+		VMName:   m.vm.Name,
+		Status:   status,
+		Message:  message,
+		Priority: notification.PriorityNormal,
+		Incident: m.incidentID(),
+	})
+}
+
+func describeStuckAction(action config.StuckAction) string {
+	switch action {
+	case config.StuckStart:
+		return "повторный запуск"
+	case config.StuckStop:
+		return "повторная остановка"
+	case config.StuckRestart:
+		return "перезапуск"
+	case config.StuckStopStart:
+		return "остановка и запуск"
+	default:
+		return "только оповещение"
+	}
+}
@@ -0,0 +1,98 @@
+package monitoring
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func stuckPolicy(status types.VMStatus, action config.StuckAction) config.Policy {
+	return config.Policy{
+		GracePeriod:  time.Nanosecond,
+		Timeouts:     map[types.VMStatus]time.Duration{status: time.Nanosecond},
+		StuckActions: map[types.VMStatus]config.StuckAction{status: action},
+	}
+}
+
+func countMessages(sent *captureNotifier, substr string) int {
+	n := 0
+	for _, notif := range sent.notifications() {
+		if strings.Contains(notif.Message, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestVMMonitor_StuckStopStart(t *testing.T) {
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusStopping}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	m := NewVMMonitor(&config.VM{Name: "vm-1"}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.SetPolicy(stuckPolicy(types.StatusStopping, config.StuckStopStart))
+
+	m.check(context.Background()) // Unknown → Stopping
+	m.check(context.Background()) // stuck: alert and stop
+	if backend.stopCount() != 1 {
+		t.Fatalf("expected one stop, got %d", backend.stopCount())
+	}
+
+	// Stopped: started right away instead of being alerted as a failure
+	backend.setStatus(types.StatusStopped)
+	m.check(context.Background())
+	if backend.startCount() != 1 {
+		t.Fatalf("expected a start once stopped, got %d", backend.startCount())
+	}
+
+	backend.setStatus(types.StatusRunning)
+	m.check(context.Background())
+
+	queue.Stop()
+	for substr, want := range map[string]int{
+		"застряла":                    1,
+		"остановка и запуск":          2, // the alert names the action, the outcome refers to it
+		"запускается после зависания": 1,
+		"снова работает":              1,
+		"СБОЙ":                        0,
+	} {
+		if got := countMessages(sent, substr); got != want {
+			t.Errorf("expected %d messages containing %q, got %d", want, substr, got)
+		}
+	}
+}
+
+func TestVMMonitor_StuckActionOncePerStatus(t *testing.T) {
+	var configMu sync.Mutex
+	backend := &fakeBackend{status: types.StatusStarting}
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+
+	m := NewVMMonitor(&config.VM{Name: "vm-1"}, backend, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.SetPolicy(stuckPolicy(types.StatusStarting, config.StuckRestart))
+
+	for i := 0; i < 5; i++ {
+		m.check(context.Background())
+		time.Sleep(time.Millisecond)
+	}
+
+	queue.Stop()
+	if backend.restartCount() != 1 {
+		t.Errorf("expected one restart, got %d", backend.restartCount())
+	}
+	if got := countMessages(sent, "застряла"); got != 1 {
+		t.Errorf("expected one stuck alert, got %d", got)
+	}
+	if got := countMessages(sent, "не помогло"); got != 1 {
+		t.Errorf("expected one follow-up that the restart did not help, got %d", got)
+	}
+}
@@ -8,8 +8,12 @@ import (
 
 	"github.com/fxfuren/yandex-watcher-bot/internal/client"
 	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
 	"github.com/fxfuren/yandex-watcher-bot/internal/network"
 	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/probe"
+	"github.com/fxfuren/yandex-watcher-bot/internal/schedule"
+	"github.com/fxfuren/yandex-watcher-bot/internal/state"
 	"github.com/fxfuren/yandex-watcher-bot/internal/types"
 	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
 )
@@ -17,23 +21,86 @@ import (
 // VMMonitor manages monitoring for a single VM
 type VMMonitor struct {
 	vm               *config.VM
-	client           *client.YandexClient
+	client           client.Backend
 	notifier         *notification.NotificationQueue
 	minInterval      time.Duration
 	maxInterval      time.Duration
 	currentStatus    types.VMStatus
 	lastStatusTime   time.Time
-	lastAPICheck     time.Time // Track last API check time
-	gracePeriodUntil time.Time // Skip checks until this time (for VM startup)
+	lastAPICheck     time.Time       // Track last API check time
+	gracePeriodUntil time.Time       // Skip checks until this time (for VM startup)
+	incident         *Incident       // Open incident, nil while the VM is healthy
+	lastIncident     *Incident       // Most recently resolved incident
+	pendingEvents    []IncidentEvent // Events of the current check, for an incident it may open
+	autoStarts       []time.Time     // Automatic starts within the restart budget window
+	crashLooping     bool            // Restart budget exhausted, automatic starts suspended
+	pausedUntil      time.Time       // Runtime pause set by an operator
+	pausedBy         string
+	pauseReason      string
+	inMaintenance    bool               // Paused or inside a maintenance window as of the last check
+	mutedUntil       time.Time          // Notifications muted with the alert button
+	desired          types.DesiredState // vm.DesiredState, stopped outside the power schedule
+	powerActionAt    time.Time          // Last scheduled start or stop
+	appDown          bool               // Running, but the VM's probes fail
+	appAlerted       bool               // The application failure was alerted and opened an incident
+	appFailure       string             // Failing probes and their errors
+	appDownSince     time.Time          // When the application failure was alerted
+	remedy           remedyStage        // How far the remediation ladder got
+	restartedAt      time.Time          // Restart by the remediation ladder
+	stuckSince       time.Time          // Entry into the last stuck status that was handled
+	stuckAction      config.StuckAction // Action taken for it, until the VM settles
+	stuckActedAt     time.Time          // When the action was requested
+	stuckReported    bool               // The action was reported as not helping
+	startWhenStopped bool               // stop_start: start the VM once it has stopped
+	probeFailures    map[string]int     // Consecutive failures per probe name
+	pingFailures     int                // Consecutive failed pings of a running VM
+	flapHistory      flapHistory        // Up or down at each of the last checks
+	flapPercent      float64            // Weighted percentage of state changes in flapHistory
+	flapping         bool               // Failure and recovery alerts are suppressed
+	lastTick         time.Time          // Last iteration of the monitor loop, for liveness
+	lastCheck        time.Time          // Last completed check, zero until the first one
 	mu               sync.RWMutex
 	configMu         *sync.Mutex
 	ipUpdateChan     chan string
+	checkNow         chan struct{}
+	store            *state.Store
+	globalPolicy     config.Policy      // guarded by configMu, like vm
+	windows          []*schedule.Window // parsed vm.Maintenance, guarded by configMu
+	hours            *schedule.Hours    // parsed vm.Schedule, guarded by configMu
+	probes           []*probe.Probe     // parsed vm.Probes, guarded by configMu
+}
+
+// VMState is a point-in-time snapshot of a monitored VM
+type VMState struct {
+	Name             string
+	Status           types.VMStatus
+	Since            time.Time
+	IP               string
+	LastAPICheck     time.Time
+	GracePeriodUntil time.Time
+	IncidentStart    time.Time
+	Incident         *Incident // Open incident, nil while the VM is healthy
+	LastIncident     *Incident // Most recently resolved incident
+	CrashLooping     bool
+	InMaintenance    bool
+	PausedUntil      time.Time
+	PausedBy         string
+	PauseReason      string
+	Desired          types.DesiredState
+	Scheduled        bool
+	AppDown          bool
+	AppFailure       string
+	AppDownSince     time.Time
+	RestartedAt      time.Time
+	RemedyExhausted  bool
+	Flapping         bool
+	FlapPercent      float64
 }
 
 // NewVMMonitor creates a new VM monitor
 func NewVMMonitor(
 	vm *config.VM,
-	client *client.YandexClient,
+	client client.Backend,
 	notifier *notification.NotificationQueue,
 	minInterval, maxInterval time.Duration,
 	configMu *sync.Mutex,
@@ -46,10 +113,184 @@ func NewVMMonitor(
 		minInterval:    minInterval,
 		maxInterval:    maxInterval,
 		currentStatus:  types.StatusUnknown,
+		desired:        types.DesiredRunning,
 		lastStatusTime: time.Now(),
 		configMu:       configMu,
 		ipUpdateChan:   ipUpdateChan,
+		checkNow:       make(chan struct{}, 1),
+	}
+}
+
+// Name returns the name of the monitored VM
+func (m *VMMonitor) Name() string {
+	return m.vm.Name
+}
+
+// State returns a snapshot of the monitor's current view of the VM
+func (m *VMMonitor) State() VMState {
+	ip := m.ip()
+	scheduled := m.powerHours() != nil
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	incidentStart := time.Time{}
+	if m.incident != nil {
+		incidentStart = m.incident.OpenedAt
+	}
+
+	return VMState{
+		Name:             m.vm.Name,
+		Status:           m.currentStatus,
+		Since:            m.lastStatusTime,
+		IP:               ip,
+		LastAPICheck:     m.lastAPICheck,
+		GracePeriodUntil: m.gracePeriodUntil,
+		IncidentStart:    incidentStart,
+		Incident:         m.incident.clone(),
+		LastIncident:     m.lastIncident.clone(),
+		CrashLooping:     m.crashLooping,
+		InMaintenance:    m.inMaintenance,
+		PausedUntil:      m.pausedUntil,
+		PausedBy:         m.pausedBy,
+		PauseReason:      m.pauseReason,
+		Desired:          m.desired,
+		Scheduled:        scheduled,
+		AppDown:          m.appDown,
+		AppFailure:       m.appFailure,
+		AppDownSince:     m.appDownSince,
+		RestartedAt:      m.restartedAt,
+		RemedyExhausted:  m.remedy == remedyExhausted,
+		Flapping:         m.flapping,
+		FlapPercent:      m.flapPercent,
+	}
+}
+
+// SetPolicy sets the global policy; the VM's own policy overrides it
+func (m *VMMonitor) SetPolicy(global config.Policy) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	m.globalPolicy = global
+}
+
+// policy returns the effective check policy of the VM
+func (m *VMMonitor) policy() config.Policy {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	return m.globalPolicy.Merge(m.vm.Policy)
+}
+
+// UseStore restores the VM's saved state from store, if any, and persists
+// every later change to it. It must be called before Start.
+func (m *VMMonitor) UseStore(store *state.Store) {
+	m.store = store
+
+	rec, ok := store.Get(m.vm.Name)
+	if !ok {
+		return
 	}
+
+	m.configMu.Lock()
+	if m.vm.IP == "" {
+		m.vm.IP = rec.IP
+	}
+	m.configMu.Unlock()
+
+	m.mu.Lock()
+	m.currentStatus = rec.Status
+	m.lastStatusTime = rec.Since
+	m.gracePeriodUntil = rec.GracePeriodUntil
+	m.incident = restoreIncident(m.vm.Name, rec)
+	m.autoStarts = rec.AutoStarts
+	m.crashLooping = rec.CrashLooping
+	m.pausedUntil = rec.PausedUntil
+	m.pausedBy = rec.PausedBy
+	m.pauseReason = rec.PauseReason
+	m.inMaintenance = rec.InMaintenance
+	m.mutedUntil = rec.MutedUntil
+	m.mu.Unlock()
+
+	if time.Now().Before(rec.MutedUntil) {
+		m.notifier.Mute(m.vm.Name, rec.MutedUntil)
+	}
+
+	metrics.SetVMStatus(m.vm.Name, string(rec.Status))
+	setCrashLoopingMetric(m.vm.Name, rec.CrashLooping)
+	logger.Info("♻️ Restored VM state",
+		"vm", m.vm.Name,
+		"status", rec.Status,
+		"since", rec.Since.Format(time.RFC3339),
+	)
+}
+
+// persist saves the current state if a store is attached
+func (m *VMMonitor) persist() {
+	if m.store == nil {
+		return
+	}
+
+	ip := m.ip()
+
+	m.mu.RLock()
+	rec := state.VMRecord{
+		Status:           m.currentStatus,
+		Since:            m.lastStatusTime,
+		GracePeriodUntil: m.gracePeriodUntil,
+		IP:               ip,
+		AutoStarts:       append([]time.Time(nil), m.autoStarts...),
+		CrashLooping:     m.crashLooping,
+		PausedUntil:      m.pausedUntil,
+		PausedBy:         m.pausedBy,
+		PauseReason:      m.pauseReason,
+		InMaintenance:    m.inMaintenance,
+	}
+	if time.Now().Before(m.mutedUntil) {
+		rec.MutedUntil = m.mutedUntil
+	}
+	saveIncident(m.incident, &rec)
+	m.mu.RUnlock()
+
+	if err := m.store.Put(m.vm.Name, rec); err != nil {
+		logger.Error("Failed to save VM state",
+			"vm", m.vm.Name,
+			"error", err,
+		)
+	}
+}
+
+// TriggerCheck asks the monitor loop to run a check as soon as possible.
+// The grace period is cleared so the check is not skipped.
+func (m *VMMonitor) TriggerCheck() {
+	m.mu.Lock()
+	m.gracePeriodUntil = time.Time{}
+	m.mu.Unlock()
+	m.persist()
+
+	select {
+	case m.checkNow <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+}
+
+// StartNow starts the VM on operator request, regardless of its last known status.
+// It also refills the restart budget, resuming automatic starts of a crash-looping VM.
+func (m *VMMonitor) StartNow(ctx context.Context) error {
+	m.mu.Lock()
+	wasLooping := m.crashLooping
+	m.autoStarts = nil
+	m.crashLooping = false
+	m.mu.Unlock()
+
+	if wasLooping {
+		logger.Info("🔁 Restart budget reset by operator",
+			"vm", m.vm.Name,
+		)
+		setCrashLoopingMetric(m.vm.Name, false)
+	}
+	m.persist()
+
+	return m.startVM(ctx, startManual)
 }
 
 // Start begins monitoring the VM
@@ -57,6 +298,7 @@ func (m *VMMonitor) Start(ctx context.Context) {
 	logger.Info("Starting VM monitor",
 		"vm", m.vm.Name,
 	)
+	m.tick()
 
 	// Run first check asynchronously to allow all monitors to start in parallel
 	go m.check(ctx)
@@ -74,11 +316,46 @@ func (m *VMMonitor) Start(ctx context.Context) {
 		case <-ticker.C:
 			m.check(ctx)
 			ticker.Reset(m.getCurrentInterval())
+		case <-m.checkNow:
+			m.check(ctx)
+			ticker.Reset(m.getCurrentInterval())
 		}
+		m.tick()
 	}
 }
 
+// Heartbeat returns when the monitor loop last went round, the interval it is
+// waiting for, and when a check last completed (zero before the first one)
+func (m *VMMonitor) Heartbeat() (lastTick time.Time, interval time.Duration, lastCheck time.Time) {
+	interval = m.getCurrentInterval()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastTick, interval, m.lastCheck
+}
+
+func (m *VMMonitor) tick() {
+	m.mu.Lock()
+	m.lastTick = time.Now()
+	m.mu.Unlock()
+}
+
 func (m *VMMonitor) check(ctx context.Context) {
+	defer func() {
+		m.mu.Lock()
+		m.lastCheck = time.Now()
+		m.mu.Unlock()
+	}()
+
+	// Events noted before this check do not belong to an incident it opens
+	m.mu.Lock()
+	m.pendingEvents = nil
+	m.mu.Unlock()
+
+	m.updateMaintenance()
+	m.updateDesired()
+	m.escalate()
+
 	vmName := m.vm.Name
 	currentStatus := m.getCurrentStatus()
 
@@ -97,6 +374,12 @@ func (m *VMMonitor) check(ctx context.Context) {
 		return
 	}
 
+	// Count the outcome towards flap detection once the check has acted
+	defer m.recordFlap()
+
+	// Reconcile towards the desired state using the status this check observes
+	defer m.reconcile(ctx)
+
 	logger.Info("🔍 Checking VM",
 		"vm", vmName,
 		"status", currentStatus,
@@ -105,38 +388,49 @@ func (m *VMMonitor) check(ctx context.Context) {
 	// Strategy: Ping first (free), API only if needed (like Python version)
 	// This saves ~90% of API calls when VMs are stable
 
-	knownIP := m.vm.IP
+	knownIP := m.ip()
 	needAPICheck := false
 
 	// 1. Try ping first if we have IP
 	if knownIP != "" {
-		pingSuccess, _ := network.PingHost(ctx, knownIP)
+		stats, _ := network.PingHostStats(ctx, knownIP)
+		pingSuccess := stats.Received > 0
 
 		if pingSuccess {
+			metrics.Pings.Inc(m.vm.Name, "success")
+			metrics.VMPingRTT.Set(stats.AvgRTT.Seconds(), m.vm.Name)
+		} else {
+			metrics.Pings.Inc(m.vm.Name, "failure")
+		}
+		if stats.Sent > 0 {
+			metrics.VMPingLoss.Set(stats.Loss(), m.vm.Name)
+		}
+
+		if pingSuccess {
+			m.resetPingFailures()
 			if currentStatus != types.StatusRunning {
 				// VM recovered! Update status and send notification
 				oldStatus := currentStatus
-				m.setStatus(types.StatusRunning)
-
-				// Clear grace period since VM is now responding
-				m.mu.Lock()
-				m.gracePeriodUntil = time.Time{}
-				m.mu.Unlock()
+				m.note("Ping %s OK", knownIP)
+				resolved := m.setStatus(types.StatusRunning)
 
 				// Only send notification if this is a real recovery (not initial startup)
-				if oldStatus != types.StatusUnknown {
+				if m.announceRecovery(oldStatus, resolved != nil) {
 					logger.Info("✅ VM recovered via ping",
 						"vm", vmName,
 						"ip", knownIP,
 						"old_status", oldStatus,
 					)
 
-					message := fmt.Sprintf("✅ ВОССТАНОВЛЕНИЕ: ВМ *%s* снова в строю.\n\nПроверка: Ping OK на %s", m.vm.Name, knownIP)
-					m.notifier.Enqueue(notification.Notification{
+					message := fmt.Sprintf("✅ ВОССТАНОВЛЕНИЕ: ВМ *%s* снова в строю.\n\nПроверка: Ping OK на %s", m.vm.Name, knownIP) +
+						recoveryDetails(resolved)
+					m.notify(notification.Notification{
 						VMName:   m.vm.Name,
 						Status:   types.StatusRunning,
 						Message:  message,
 						Priority: notification.PriorityCritical,
+						Incident: incidentKey(resolved),
+						Resolve:  true,
 					})
 				} else {
 					logger.Info("✅ VM initialized as Running",
@@ -148,15 +442,31 @@ func (m *VMMonitor) check(ctx context.Context) {
 				logger.Info("🏓 Ping OK",
 					"vm", vmName,
 					"ip", knownIP,
+					"rtt", stats.AvgRTT.Round(time.Microsecond),
+					"loss", fmt.Sprintf("%d/%d", stats.Sent-stats.Received, stats.Sent),
 				)
 			}
+			m.checkProbes(ctx)
 			return // Ping OK, skip API
 		} else {
+			failures, threshold := m.notePingFailure(), m.policy().PingThreshold()
+			if currentStatus == types.StatusRunning && failures < threshold {
+				// Ride out a flaky network before asking the API
+				logger.Warn("⚠️ Ping failed, waiting for confirmation",
+					"vm", vmName,
+					"ip", knownIP,
+					"failures", fmt.Sprintf("%d/%d", failures, threshold),
+				)
+				return
+			}
+
 			// Ping failed, need to check API
 			logger.Warn("⚠️ Ping failed, checking API",
 				"vm", vmName,
 				"ip", knownIP,
 			)
+			m.note("Ping %s не прошёл", knownIP)
+			m.resetPingFailures()
 			needAPICheck = true
 		}
 	} else {
@@ -170,7 +480,8 @@ func (m *VMMonitor) check(ctx context.Context) {
 		m.lastAPICheck = time.Now()
 		m.mu.Unlock()
 
-		info, err := m.client.GetVMInfo(ctx, m.vm.URL)
+		backend, target := m.api()
+		info, err := backend.GetVMInfo(ctx, target)
 		if err != nil {
 			logger.Error("❌ Failed to get VM info",
 				"vm", vmName,
@@ -186,12 +497,15 @@ func (m *VMMonitor) check(ctx context.Context) {
 		)
 
 		// Update IP if changed or discovered
-		if info.IP != "" && info.IP != m.vm.IP {
+		if info.IP != "" {
 			m.updateIP(info.IP)
 		}
 
 		// Handle status change based on API response
 		m.handleStatusChange(ctx, info.Status)
+		if m.getCurrentStatus() == types.StatusRunning {
+			m.checkProbes(ctx)
+		}
 	}
 }
 
@@ -199,15 +513,18 @@ func (m *VMMonitor) handleRunningState(ctx context.Context, details string) {
 	oldStatus := m.getCurrentStatus()
 
 	if oldStatus != types.StatusRunning {
-		m.setStatus(types.StatusRunning)
+		resolved := m.setStatus(types.StatusRunning)
 
-		if oldStatus != types.StatusUnknown {
-			message := fmt.Sprintf("✅ ВОССТАНОВЛЕНИЕ: ВМ *%s* снова в строю.\n\n%s", m.vm.Name, details)
-			m.notifier.Enqueue(notification.Notification{
+		if m.announceRecovery(oldStatus, resolved != nil) {
+			message := fmt.Sprintf("✅ ВОССТАНОВЛЕНИЕ: ВМ *%s* снова в строю.\n\n%s", m.vm.Name, details) +
+				recoveryDetails(resolved)
+			m.notify(notification.Notification{
 				VMName:   m.vm.Name,
 				Status:   types.StatusRunning,
 				Message:  message,
 				Priority: notification.PriorityNormal,
+				Incident: incidentKey(resolved),
+				Resolve:  true,
 			})
 			logger.Info("✅ VM recovered",
 				"vm", m.vm.Name,
@@ -234,7 +551,7 @@ func (m *VMMonitor) handleStatusChange(ctx context.Context, newStatus types.VMSt
 		)
 	}
 
-	m.setStatus(newStatus)
+	resolved := m.setStatus(newStatus)
 
 	// Add emoji based on status
 	var statusEmoji string
@@ -255,24 +572,33 @@ func (m *VMMonitor) handleStatusChange(ctx context.Context, newStatus types.VMSt
 		"new_status", newStatus,
 	)
 
-	if newStatus.ShouldStartVM() {
+	if newStatus.IsCritical() {
+		if newStatus == types.StatusStopped && m.takeStartWhenStopped() {
+			_ = m.startVM(ctx, startStuck)
+			return
+		}
+		if m.expectsDown() {
+			logger.Info("💤 VM is expected to be down, not starting",
+				"vm", m.vm.Name,
+				"status", newStatus,
+			)
+			return
+		}
 		m.handleCriticalStatus(ctx, newStatus)
 		return
 	}
 
 	if newStatus == types.StatusRunning {
-		// Clear grace period since VM is now running
-		m.mu.Lock()
-		m.gracePeriodUntil = time.Time{}
-		m.mu.Unlock()
-
-		if oldStatus != types.StatusUnknown {
-			message := fmt.Sprintf("✅ ВОССТАНОВЛЕНИЕ: ВМ *%s* снова в строю.\n\nСтатус API: Running", m.vm.Name)
-			m.notifier.Enqueue(notification.Notification{
+		if m.announceRecovery(oldStatus, resolved != nil) {
+			message := fmt.Sprintf("✅ ВОССТАНОВЛЕНИЕ: ВМ *%s* снова в строю.\n\nСтатус API: Running", m.vm.Name) +
+				recoveryDetails(resolved)
+			m.notify(notification.Notification{
 				VMName:   m.vm.Name,
 				Status:   types.StatusRunning,
 				Message:  message,
 				Priority: notification.PriorityCritical, // Always send recovery notifications
+				Incident: incidentKey(resolved),
+				Resolve:  true,
 			})
 		}
 		return
@@ -305,25 +631,138 @@ func (m *VMMonitor) handleCriticalStatus(ctx context.Context, status types.VMSta
 		emoji = "⚠️"
 	}
 
-	message := fmt.Sprintf("%s СБОЙ: ВМ *%s* недоступна.\n\nСтатус: %s", emoji, vmName, status)
-	m.notifier.Enqueue(notification.Notification{
+	desired := m.desiredState()
+	incidentID := m.incidentID()
+	message := fmt.Sprintf("%s СБОЙ: ВМ *%s* недоступна.\n\nСтатус: %s\nИнцидент: `%s`", emoji, vmName, status, incidentID)
+	if desired == types.DesiredUnmanaged {
+		message += "\nТолько наблюдение: бот не запускает эту ВМ."
+	}
+	m.notify(notification.Notification{
 		VMName:   vmName,
 		Status:   status,
 		Message:  message,
 		Priority: notification.PriorityCritical,
+		Keyboard: notification.NewAlertKeyboard(vmName),
+		Incident: incidentID,
 	})
 
-	m.startVM(ctx)
+	if !status.ShouldStartVM(desired) {
+		logger.Info("👁️ VM is observe-only, not starting",
+			"vm", vmName,
+			"desired_state", desired,
+		)
+		return
+	}
+	if !m.takeAutoStart() {
+		return
+	}
+	_ = m.startVM(ctx, startAuto)
+}
+
+// takeAutoStart spends one automatic start from the restart budget.
+// When the budget is exhausted the VM is marked as crash-looping, an operator
+// is asked to step in once, and false is returned.
+func (m *VMMonitor) takeAutoStart() bool {
+	maxStarts, window := m.policy().Budget()
+	now := time.Now()
+
+	m.mu.Lock()
+	recent := m.autoStarts[:0]
+	for _, t := range m.autoStarts {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	m.autoStarts = recent
+
+	if len(recent) >= maxStarts {
+		alreadyLooping := m.crashLooping
+		m.crashLooping = true
+		m.mu.Unlock()
+
+		if !alreadyLooping {
+			m.escalateCrashLoop(maxStarts, window)
+			m.persist()
+		}
+		logger.Warn("🔁 Restart budget exhausted, not starting VM",
+			"vm", m.vm.Name,
+			"max_starts", maxStarts,
+			"window", window,
+		)
+		metrics.StartAttempts.Inc(m.vm.Name, "budget_exhausted")
+		return false
+	}
+
+	wasLooping := m.crashLooping
+	m.crashLooping = false
+	m.autoStarts = append(m.autoStarts, now)
+	m.mu.Unlock()
+
+	if wasLooping {
+		logger.Info("🔁 Restart budget available again, resuming automatic starts",
+			"vm", m.vm.Name,
+		)
+		setCrashLoopingMetric(m.vm.Name, false)
+	}
+	m.persist()
+	return true
 }
 
-func (m *VMMonitor) startVM(ctx context.Context) {
+// escalateCrashLoop asks a human to look at a VM that keeps going down after starts
+func (m *VMMonitor) escalateCrashLoop(maxStarts int, window time.Duration) {
+	vmName := m.vm.Name
+	setCrashLoopingMetric(vmName, true)
+
+	logger.Error("🔁 VM is crash-looping, automatic starts suspended",
+		"vm", vmName,
+		"max_starts", maxStarts,
+		"window", window,
+	)
+	m.note("Crash loop: автозапуск приостановлен")
+
+	message := fmt.Sprintf("🔁 CRASH LOOP: ВМ *%s* падает после запуска: автозапусков за %[3]s — %[2]d.\n\n"+
+		"Автозапуск приостановлен, нужна помощь человека. Кнопка «Запустить» или /start %[1]s возобновит автозапуск.",
+		vmName, maxStarts, window)
+	m.notifier.Enqueue(notification.Notification{
+		VMName:   vmName,
+		Status:   types.StatusCrashed,
+		Message:  message,
+		Priority: notification.PriorityCritical,
+		Keyboard: notification.NewAlertKeyboard(vmName),
+		Incident: m.incidentID(),
+	})
+}
+
+func setCrashLoopingMetric(vmName string, looping bool) {
+	value := 0.0
+	if looping {
+		value = 1
+	}
+	metrics.VMCrashLooping.Set(value, vmName)
+}
+
+// startTrigger says why a VM is being started
+type startTrigger string
+
+const (
+	startAuto      startTrigger = "auto"
+	startManual    startTrigger = "manual"
+	startScheduled startTrigger = "schedule"
+	startStuck     startTrigger = "stuck"
+)
+
+// startVM issues a start request
+func (m *VMMonitor) startVM(ctx context.Context, trigger startTrigger) error {
 	vmName := m.vm.Name
 	logger.Info("🔧 Attempting to start VM",
 		"vm", vmName,
+		"trigger", trigger,
 	)
 
+	alreadyRunning := false
 	err := client.WithRetry(ctx, 3, func() error {
-		resp, err := m.client.StartVM(ctx, m.vm.URL)
+		backend, target := m.api()
+		resp, err := backend.StartVM(ctx, target)
 		if err != nil {
 			return err
 		}
@@ -332,32 +771,52 @@ func (m *VMMonitor) startVM(ctx context.Context) {
 			return fmt.Errorf("%s", resp.Message)
 		}
 
-		if resp.IP != "" && resp.IP != m.vm.IP {
+		if resp.IP != "" {
 			m.updateIP(resp.IP)
 		}
 
+		alreadyRunning = resp.WasAlreadyRunning
 		if resp.WasAlreadyRunning {
 			logger.Info("ℹ️ VM was already running",
 				"vm", vmName,
 			)
 		} else {
 			// VM is starting - set grace period to avoid unnecessary API calls
-			gracePeriod := 60 * time.Second
+			gracePeriod := m.policy().Grace()
 			m.mu.Lock()
 			m.gracePeriodUntil = time.Now().Add(gracePeriod)
 			m.mu.Unlock()
+			m.persist()
 
 			logger.Info("🚀 VM start initiated",
 				"vm", vmName,
 				"grace_period", gracePeriod,
 			)
-
-			message := fmt.Sprintf("🚀 Автозапуск: ВМ *%s* запускается через API.", vmName)
-			m.notifier.Enqueue(notification.Notification{
+			m.note("Запуск ВМ (%s)", trigger)
+			m.watchOperation(ctx, backend, "start", resp.OperationID)
+
+			var message string
+			switch trigger {
+			case startManual:
+				message = fmt.Sprintf("🚀 Запуск: ВМ *%s* запускается по команде оператора.", vmName)
+			case startScheduled:
+				message = fmt.Sprintf("🚀 По расписанию: ВМ *%s* запускается.", vmName)
+			case startStuck:
+				message = fmt.Sprintf("🚀 Запуск: ВМ *%s* запускается после зависания.", vmName)
+			default:
+				message = fmt.Sprintf("🚀 Автозапуск: ВМ *%s* запускается через API.", vmName)
+			}
+			notify := m.notifier.Enqueue
+			if trigger == startAuto {
+				// Part of the failure storm while the VM is flapping
+				notify = m.notify
+			}
+			notify(notification.Notification{
 				VMName:   vmName,
 				Status:   types.StatusStarting,
 				Message:  message,
 				Priority: notification.PriorityCritical,
+				Incident: m.incidentID(),
 			})
 		}
 
@@ -365,46 +824,80 @@ func (m *VMMonitor) startVM(ctx context.Context) {
 	})
 
 	if err != nil {
+		metrics.StartAttempts.Inc(vmName, "failed")
 		logger.Error("❌ Failed to start VM",
 			"vm", vmName,
 			"error", err,
 		)
+		m.note("Запуск не удался: %v", err)
+		return fmt.Errorf("failed to start VM: %w", err)
+	}
+
+	if alreadyRunning {
+		metrics.StartAttempts.Inc(vmName, "already_running")
+	} else {
+		metrics.StartAttempts.Inc(vmName, "started")
 	}
+	return nil
 }
 
-func (m *VMMonitor) checkStuckStatus(ctx context.Context) {
-	status := m.getCurrentStatus()
+// api returns the backend controlling this VM and the identifier it uses for it
+func (m *VMMonitor) api() (client.Backend, string) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
 
-	if !status.IsTransitional() {
-		return
+	if m.vm.UsesComputeAPI() {
+		return m.client, m.vm.InstanceID
 	}
+	return m.client, m.vm.URL
+}
 
-	timeout := status.GetTimeout()
-	if timeout == 0 {
-		return
-	}
+// ip returns the last known IP of the VM
+func (m *VMMonitor) ip() string {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	return m.vm.IP
+}
 
-	m.mu.RLock()
-	timeSinceChange := time.Since(m.lastStatusTime)
-	m.mu.RUnlock()
+// VM returns a copy of the monitored VM's current settings
+func (m *VMMonitor) VM() config.VM {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	return *m.vm
+}
 
-	if timeSinceChange > timeout {
-		logger.Warn("⏰ VM stuck in transitional status",
-			"vm", m.vm.Name,
-			"status", status,
-			"duration", timeSinceChange,
-		)
+// UpdateVM applies changed settings from a reloaded configuration without
+// touching the monitor's state. An empty IP keeps the last known one.
+func (m *VMMonitor) UpdateVM(vm config.VM, backend client.Backend) {
+	m.configMu.Lock()
+	m.vm.URL = vm.URL
+	m.vm.InstanceID = vm.InstanceID
+	m.vm.Policy = vm.Policy
+	m.vm.Maintenance = vm.Maintenance
+	m.windows = nil
+	m.vm.Schedule = vm.Schedule
+	m.hours = nil
+	m.vm.DesiredState = vm.DesiredState
+	m.vm.Probes = vm.Probes
+	m.vm.ProbesRequired = vm.ProbesRequired
+	m.probes = nil
+	m.client = backend
+	ipChanged := vm.IP != "" && vm.IP != m.vm.IP
+	if ipChanged {
+		m.vm.IP = vm.IP
+	}
+	m.configMu.Unlock()
 
-		message := fmt.Sprintf("⚠️ ВНИМАНИЕ: ВМ *%s* застряла в статусе %s более %v",
-			m.vm.Name, status, timeSinceChange.Round(time.Second))
+	// Probes may have been renamed or removed
+	m.mu.Lock()
+	m.probeFailures = nil
+	m.mu.Unlock()
 
-		m.notifier.Enqueue(notification.Notification{
-			VMName:   m.vm.Name,
-			Status:   status,
-			Message:  message,
-			Priority: notification.PriorityNormal,
-		})
+	if ipChanged {
+		m.persist()
 	}
+	// Re-check against the new settings right away
+	m.TriggerCheck()
 }
 
 func (m *VMMonitor) getCurrentStatus() types.VMStatus {
@@ -413,16 +906,67 @@ func (m *VMMonitor) getCurrentStatus() types.VMStatus {
 	return m.currentStatus
 }
 
-func (m *VMMonitor) setStatus(status types.VMStatus) {
+// setStatus records a status change and opens or resolves the incident it
+// implies. It returns the incident the change resolved, if any.
+func (m *VMMonitor) setStatus(status types.VMStatus) *Incident {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.currentStatus = status
 	m.lastStatusTime = time.Now()
+	metrics.SetVMStatus(m.vm.Name, string(status))
+	m.pingFailures = 0
+	if status.IsCritical() {
+		// The VM itself is down; its probes are re-evaluated once it runs again.
+		// Transitional statuses keep them: a restart is part of remediation.
+		m.resetAppLocked()
+		m.probeFailures = nil
+	}
+
+	if status.IsCritical() && !m.expectsDownLocked() {
+		m.openIncidentLocked(m.lastStatusTime)
+	}
+	m.noteLocked("Статус: %s", status)
+
+	var resolved *Incident
+	if status == types.StatusRunning {
+		// The VM is responding: stop waiting for it to boot and close the
+		// incident, unless its application is still down
+		m.gracePeriodUntil = time.Time{}
+		if !m.appAlerted {
+			resolved = m.resolveIncidentLocked(m.lastStatusTime)
+		}
+	}
+	m.mu.Unlock()
+
+	m.resolveStuck(status)
+	m.persist()
+	return resolved
 }
 
 func (m *VMMonitor) getCurrentInterval() time.Duration {
 	status := m.getCurrentStatus()
-	return status.GetCheckInterval(m.minInterval, m.maxInterval)
+	if status.IsCritical() && (m.expectsDown() || m.desiredState() == types.DesiredUnmanaged) {
+		// Nothing to act on until the VM is expected up again
+		status = types.StatusRunning
+	}
+	if status == types.StatusRunning && m.isSuspect() {
+		// Confirm or clear a failure quickly
+		return m.minInterval
+	}
+	return m.policy().CheckInterval(status, m.minInterval, m.maxInterval)
+}
+
+// notePingFailure counts a failed ping and returns the number in a row
+func (m *VMMonitor) notePingFailure() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pingFailures++
+	return m.pingFailures
+}
+
+func (m *VMMonitor) resetPingFailures() {
+	m.mu.Lock()
+	m.pingFailures = 0
+	m.mu.Unlock()
 }
 
 func (m *VMMonitor) updateIP(newIP string) {
@@ -432,6 +976,8 @@ func (m *VMMonitor) updateIP(newIP string) {
 	m.configMu.Unlock()
 
 	if oldIP != newIP {
+		m.persist()
+
 		logger.Info("🌐 IP address updated",
 			"vm", m.vm.Name,
 			"old_ip", oldIP,
@@ -0,0 +1,124 @@
+package monitoring
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/config"
+	"github.com/fxfuren/yandex-watcher-bot/internal/notification"
+	"github.com/fxfuren/yandex-watcher-bot/internal/state"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestVMMonitor_StatePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := state.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var configMu sync.Mutex
+	m := NewVMMonitor(&config.VM{Name: "vm-1", IP: "10.0.0.1"}, nil, nil, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.UseStore(store)
+
+	m.setStatus(types.StatusStopped)
+	stoppedAt := m.State().Since
+
+	// A restarted process picks the incident up where it was left
+	reopened, err := state.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewVMMonitor(&config.VM{Name: "vm-1"}, nil, nil, 5*time.Second, 60*time.Second, &configMu, nil)
+	restored.UseStore(reopened)
+
+	got := restored.State()
+	if got.Status != types.StatusStopped {
+		t.Errorf("expected restored status Stopped, got %s", got.Status)
+	}
+	if !got.Since.Equal(stoppedAt) || !got.IncidentStart.Equal(stoppedAt) {
+		t.Errorf("expected since and incident start %v, got %v and %v", stoppedAt, got.Since, got.IncidentStart)
+	}
+	if got.IP != "10.0.0.1" {
+		t.Errorf("expected last known IP to be restored, got %q", got.IP)
+	}
+
+	restored.setStatus(types.StatusRunning)
+	rec, _ := reopened.Get("vm-1")
+	if rec.Status != types.StatusRunning || !rec.IncidentStart.IsZero() {
+		t.Errorf("expected recovery to close the incident, got %+v", rec)
+	}
+}
+
+func TestVMMonitor_RestartBudget(t *testing.T) {
+	var configMu sync.Mutex
+	sent := &captureNotifier{}
+	queue := notification.NewNotificationQueue(1, notification.Channel{Notifier: sent})
+	queue.Start()
+	m := NewVMMonitor(&config.VM{Name: "vm-1"}, nil, queue, 5*time.Second, 60*time.Second, &configMu, nil)
+	m.SetPolicy(config.Policy{RestartBudget: config.RestartBudget{MaxStarts: 2, Window: time.Hour}})
+
+	if !m.takeAutoStart() || !m.takeAutoStart() {
+		t.Fatal("expected the first two automatic starts to be allowed")
+	}
+	if m.takeAutoStart() {
+		t.Fatal("expected the third automatic start to exceed the budget")
+	}
+	if !m.State().CrashLooping {
+		t.Error("expected the VM to be marked as crash-looping")
+	}
+	if m.takeAutoStart() {
+		t.Fatal("expected automatic starts to stay suspended")
+	}
+
+	// Only one escalation per crash loop
+	queue.Stop()
+	escalations := 0
+	for _, n := range sent.notifications() {
+		if strings.Contains(n.Message, "CRASH LOOP") {
+			escalations++
+		}
+	}
+	if escalations != 1 {
+		t.Errorf("expected one crash loop notification, got %d", escalations)
+	}
+
+	// Once the window slides past the old starts, the budget is available again
+	m.mu.Lock()
+	for i := range m.autoStarts {
+		m.autoStarts[i] = m.autoStarts[i].Add(-2 * time.Hour)
+	}
+	m.mu.Unlock()
+
+	if !m.takeAutoStart() {
+		t.Fatal("expected the budget to refill after the window")
+	}
+	if m.State().CrashLooping {
+		t.Error("expected crash-looping to be cleared")
+	}
+}
+
+// captureNotifier records delivered notifications
+type captureNotifier struct {
+	mu  sync.Mutex
+	got []notification.Notification
+}
+
+func (c *captureNotifier) Name() string { return "capture" }
+
+func (c *captureNotifier) Notify(ctx context.Context, n notification.Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.got = append(c.got, n)
+	return nil
+}
+
+func (c *captureNotifier) notifications() []notification.Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]notification.Notification(nil), c.got...)
+}
@@ -0,0 +1,233 @@
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	icmpEchoReply   = 0
+	icmpEchoRequest = 8
+	icmpHeaderLen   = 8
+)
+
+// icmpMode is the kind of socket used for native pings
+type icmpMode string
+
+const (
+	// icmpDatagram uses unprivileged ICMP sockets (Linux ping_group_range, macOS)
+	icmpDatagram icmpMode = "datagram"
+	// icmpRaw uses raw sockets and needs root or CAP_NET_RAW
+	icmpRaw icmpMode = "raw"
+)
+
+var errUnsupported = errors.New("not supported on this platform")
+
+// echoID distinguishes our echo requests from other processes' on raw sockets.
+// Datagram sockets get their ID assigned by the kernel.
+var echoID = uint32(rand.Intn(1 << 16))
+
+// listen opens an ICMPv4 socket of the given mode
+func (m icmpMode) listen() (net.PacketConn, error) {
+	if m == icmpDatagram {
+		return listenDatagramICMP()
+	}
+	return net.ListenPacket("ip4:icmp", "0.0.0.0")
+}
+
+// icmpProber pings in-process over an ICMP socket
+type icmpProber struct {
+	mode icmpMode
+}
+
+func (p *icmpProber) name() string {
+	return "icmp-" + string(p.mode)
+}
+
+// probe sends count echo requests interval apart and collects the replies
+// until all have arrived or timeout has passed since the last request
+func (p *icmpProber) probe(ctx context.Context, host string, count int, interval, timeout time.Duration) (PingStats, error) {
+	stats := PingStats{}
+
+	addr, err := resolveIPv4(ctx, host)
+	if err != nil {
+		return stats, err
+	}
+
+	conn, err := p.mode.listen()
+	if err != nil {
+		return stats, fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	defer conn.Close()
+
+	var dst net.Addr = &net.IPAddr{IP: addr}
+	if p.mode == icmpDatagram {
+		dst = &net.UDPAddr{IP: addr}
+	}
+
+	id := uint16(atomic.AddUint32(&echoID, 1))
+	sentAt := make([]time.Time, count)
+	received := make([]bool, count)
+
+	// Replies are read in the background while requests go out
+	type reply struct {
+		seq int
+		at  time.Time
+	}
+	replies := make(chan reply, count)
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			at := time.Now()
+			if !sameIP(from, addr) {
+				continue
+			}
+			replyID, seq, ok := parseEchoReply(buf[:n])
+			if !ok || (p.mode == icmpRaw && replyID != id) {
+				continue
+			}
+			select {
+			case replies <- reply{seq: int(seq), at: at}:
+			default:
+				// Duplicates beyond what we sent
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(timeout)
+	_ = conn.SetReadDeadline(deadline)
+
+	for seq := 0; seq < count; seq++ {
+		if seq > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		sentAt[seq] = time.Now()
+		if _, err := conn.WriteTo(marshalEchoRequest(id, uint16(seq)), dst); err != nil {
+			_ = conn.Close()
+			<-readDone
+			return stats, fmt.Errorf("failed to send echo request: %w", err)
+		}
+		stats.Sent++
+
+		deadline = time.Now().Add(timeout)
+		_ = conn.SetReadDeadline(deadline)
+	}
+
+	if stats.Sent == 0 {
+		return stats, ctx.Err()
+	}
+
+	record := func(r reply) {
+		if r.seq < stats.Sent && !received[r.seq] {
+			received[r.seq] = true
+			stats.add(r.at.Sub(sentAt[r.seq]))
+		}
+	}
+
+	for stats.Received < stats.Sent {
+		select {
+		case r := <-replies:
+			record(r)
+		case <-readDone:
+			// Read deadline passed: whatever has not arrived by now is lost
+			for len(replies) > 0 {
+				record(<-replies)
+			}
+			return stats, ctx.Err()
+		case <-ctx.Done():
+			_ = conn.Close()
+			<-readDone
+			return stats, ctx.Err()
+		}
+	}
+	return stats, nil
+}
+
+// marshalEchoRequest builds an ICMPv4 echo request with a timestamp payload
+func marshalEchoRequest(id, seq uint16) []byte {
+	b := make([]byte, icmpHeaderLen+8)
+	b[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(b[4:], id)
+	binary.BigEndian.PutUint16(b[6:], seq)
+	binary.BigEndian.PutUint64(b[8:], uint64(time.Now().UnixNano()))
+	binary.BigEndian.PutUint16(b[2:], checksum(b))
+	return b
+}
+
+// parseEchoReply extracts the ID and sequence of an echo reply. Some platforms
+// deliver the IPv4 header along with the message; it is skipped.
+func parseEchoReply(b []byte) (id, seq uint16, ok bool) {
+	if len(b) > 0 && b[0]>>4 == 4 {
+		headerLen := int(b[0]&0x0f) * 4
+		if len(b) < headerLen {
+			return 0, 0, false
+		}
+		b = b[headerLen:]
+	}
+
+	if len(b) < icmpHeaderLen || b[0] != icmpEchoReply || b[1] != 0 {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint16(b[4:]), binary.BigEndian.Uint16(b[6:]), true
+}
+
+// checksum is the Internet checksum (RFC 1071)
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// resolveIPv4 returns the IPv4 address of host
+func resolveIPv4(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4, nil
+		}
+		return nil, fmt.Errorf("%s is not an IPv4 address", host)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	return addrs[0].To4(), nil
+}
+
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package network
+
+import "net"
+
+// listenDatagramICMP is only available on Linux and macOS
+func listenDatagramICMP() (net.PacketConn, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEchoRequest_Checksum(t *testing.T) {
+	b := marshalEchoRequest(0x1234, 7)
+
+	if b[0] != icmpEchoRequest || b[1] != 0 {
+		t.Fatalf("unexpected type/code %d/%d", b[0], b[1])
+	}
+	// A packet with a correct checksum sums to zero
+	if sum := checksum(b); sum != 0 {
+		t.Errorf("checksum over the packet = %#x, want 0", sum)
+	}
+}
+
+func TestParseEchoReply(t *testing.T) {
+	reply := marshalEchoRequest(0x1234, 7)
+	reply[0] = icmpEchoReply
+
+	id, seq, ok := parseEchoReply(reply)
+	if !ok || id != 0x1234 || seq != 7 {
+		t.Errorf("parseEchoReply() = %#x, %d, %v", id, seq, ok)
+	}
+
+	// Same reply behind a 20-byte IPv4 header, as macOS delivers it
+	withHeader := append(make([]byte, 20), reply...)
+	withHeader[0] = 0x45
+	if id, seq, ok := parseEchoReply(withHeader); !ok || id != 0x1234 || seq != 7 {
+		t.Errorf("parseEchoReply() with IP header = %#x, %d, %v", id, seq, ok)
+	}
+
+	if _, _, ok := parseEchoReply(marshalEchoRequest(1, 1)); ok {
+		t.Error("expected an echo request not to parse as a reply")
+	}
+	if _, _, ok := parseEchoReply(reply[:4]); ok {
+		t.Error("expected a truncated reply to be rejected")
+	}
+}
+
+func TestPingStats(t *testing.T) {
+	s := PingStats{Sent: 4}
+	s.add(10 * time.Millisecond)
+	s.add(30 * time.Millisecond)
+	s.add(20 * time.Millisecond)
+
+	if s.MinRTT != 10*time.Millisecond || s.MaxRTT != 30*time.Millisecond || s.AvgRTT != 20*time.Millisecond {
+		t.Errorf("unexpected RTTs: %+v", s)
+	}
+	if s.Loss() != 0.25 {
+		t.Errorf("Loss() = %v, want 0.25", s.Loss())
+	}
+	if (PingStats{}).Loss() != 0 {
+		t.Error("expected no loss without requests")
+	}
+}
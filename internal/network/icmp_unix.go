@@ -0,0 +1,24 @@
+//go:build linux || darwin
+
+package network
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenDatagramICMP opens an unprivileged ICMPv4 socket. On Linux the
+// process group must be within net.ipv4.ping_group_range.
+func listenDatagramICMP() (net.PacketConn, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+
+	// FilePacketConn duplicates the descriptor
+	return net.FilePacketConn(f)
+}
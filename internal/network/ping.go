@@ -4,38 +4,158 @@ import (
 	"context"
 	"os/exec"
 	"runtime"
+	"sync"
 	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
 )
 
 const (
-	// PingAttempts is the number of ping attempts before declaring failure
+	// PingAttempts is the number of echo requests sent per check
 	PingAttempts = 3
-	// PingTimeout is the timeout for each ping attempt
+	// PingTimeout is how long to wait for a reply after the last request
 	PingTimeout = 2 * time.Second
+	// PingInterval is the spacing between echo requests of one check
+	PingInterval = 200 * time.Millisecond
+)
+
+// PingStats summarizes the echo requests of one check
+type PingStats struct {
+	Sent     int
+	Received int
+	MinRTT   time.Duration
+	AvgRTT   time.Duration
+	MaxRTT   time.Duration
+}
+
+// Loss returns the fraction of requests that got no reply
+func (s PingStats) Loss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Sent-s.Received) / float64(s.Sent)
+}
+
+// add records a reply with the given round-trip time
+func (s *PingStats) add(rtt time.Duration) {
+	if s.Received == 0 || rtt < s.MinRTT {
+		s.MinRTT = rtt
+	}
+	if rtt > s.MaxRTT {
+		s.MaxRTT = rtt
+	}
+	s.AvgRTT = (s.AvgRTT*time.Duration(s.Received) + rtt) / time.Duration(s.Received+1)
+	s.Received++
+}
+
+// prober sends echo requests to a host
+type prober interface {
+	name() string
+	probe(ctx context.Context, host string, count int, interval, timeout time.Duration) (PingStats, error)
+}
+
+var (
+	proberOnce    sync.Once
+	defaultProber prober
 )
 
+// selectProber picks the best available way to ping: unprivileged ICMP sockets,
+// raw sockets, or the system ping binary
+func selectProber() prober {
+	proberOnce.Do(func() {
+		for _, mode := range []icmpMode{icmpDatagram, icmpRaw} {
+			conn, err := mode.listen()
+			if err != nil {
+				logger.Debug("ICMP socket unavailable",
+					"mode", mode,
+					"error", err,
+				)
+				continue
+			}
+			_ = conn.Close()
+			defaultProber = &icmpProber{mode: mode}
+			break
+		}
+
+		if defaultProber == nil {
+			defaultProber = execProber{}
+		}
+		logger.Info("🏓 Ping method selected",
+			"method", defaultProber.name(),
+		)
+	})
+	return defaultProber
+}
+
 // PingHost checks if a host is reachable using ICMP ping
 // Sends multiple ping attempts to reduce false negatives from packet loss
 func PingHost(ctx context.Context, host string) (bool, error) {
-	for attempt := 1; attempt <= PingAttempts; attempt++ {
+	ok, _, err := PingHostWithRTT(ctx, host)
+	return ok, err
+}
+
+// PingHostWithRTT is PingHost that also reports the average round-trip time
+func PingHostWithRTT(ctx context.Context, host string) (bool, time.Duration, error) {
+	stats, err := PingHostStats(ctx, host)
+	return stats.Received > 0, stats.AvgRTT, err
+}
+
+// PingHostStats pings a host and reports round-trip times and loss.
+// If the native prober fails (e.g. the socket cannot be opened), the system
+// ping binary is used for this check instead.
+func PingHostStats(ctx context.Context, host string) (PingStats, error) {
+	p := selectProber()
+
+	stats, err := p.probe(ctx, host, PingAttempts, PingInterval, PingTimeout)
+	if err != nil && ctx.Err() == nil {
+		if _, isExec := p.(execProber); !isExec {
+			logger.Warn("⚠️ Native ping failed, falling back to ping binary",
+				"host", host,
+				"method", p.name(),
+				"error", err,
+			)
+			return execProber{}.probe(ctx, host, PingAttempts, PingInterval, PingTimeout)
+		}
+	}
+	if ctx.Err() != nil {
+		return stats, ctx.Err()
+	}
+	return stats, nil
+}
+
+// execProber runs the system ping binary, one packet per attempt, and stops
+// at the first reply
+type execProber struct{}
+
+func (execProber) name() string {
+	return "exec"
+}
+
+func (execProber) probe(ctx context.Context, host string, count int, interval, timeout time.Duration) (PingStats, error) {
+	stats := PingStats{}
+	for attempt := 1; attempt <= count; attempt++ {
 		// Create context with timeout for this attempt
-		attemptCtx, cancel := context.WithTimeout(ctx, PingTimeout)
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
 
+		start := time.Now()
 		ok := pingOnce(attemptCtx, host)
+		rtt := time.Since(start)
 		cancel()
 
+		stats.Sent++
 		if ok {
-			return true, nil
+			stats.add(rtt)
+			return stats, nil
 		}
 
 		// Check if parent context was cancelled
 		if ctx.Err() != nil {
-			return false, ctx.Err()
+			return stats, ctx.Err()
 		}
 	}
 
 	// All attempts failed
-	return false, nil
+	return stats, nil
 }
 
 // pingOnce sends a single ping packet
@@ -0,0 +1,153 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+// fakeSMTP accepts a single mail and records the dialogue
+type fakeSMTP struct {
+	listener net.Listener
+	done     chan struct{}
+	auth     string   // decoded AUTH PLAIN credentials
+	rcpts    []string // RCPT TO arguments
+	data     string   // message as received, dot-stuffing kept
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTP{listener: listener, done: make(chan struct{})}
+	go s.serve(t)
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve(t *testing.T) {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0])
+
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			fields := strings.Fields(cmd)
+			creds, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			s.auth = string(creds)
+			reply("235 Authentication successful")
+		case "MAIL":
+			reply("250 OK")
+		case "RCPT":
+			s.rcpts = append(s.rcpts, strings.TrimPrefix(cmd, "RCPT TO:"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var sb strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				sb.WriteString(line)
+			}
+			s.data = sb.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			t.Errorf("unexpected SMTP command %q", cmd)
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestEmailNotifier_SendsMail(t *testing.T) {
+	server := newFakeSMTP(t)
+
+	notifier := NewEmailNotifier("127.0.0.1", server.port(), "watchdog", "secret", "watchdog@example.com",
+		[]string{"ops@example.com", "oncall@example.com"})
+	err := notifier.Notify(context.Background(), Notification{
+		VMName:   "db",
+		Status:   types.StatusStopped,
+		Message:  "🚨 *СБОЙ*: ВМ `db` недоступна.\nСтатус: Stopped, " + EscapeMarkdown("http_check: 5*3"),
+		Priority: PriorityCritical,
+	})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	<-server.done
+
+	if server.auth != "\x00watchdog\x00secret" {
+		t.Errorf("expected PLAIN credentials, got %q", server.auth)
+	}
+	if len(server.rcpts) != 2 || server.rcpts[0] != "<ops@example.com>" || server.rcpts[1] != "<oncall@example.com>" {
+		t.Errorf("expected both recipients, got %v", server.rcpts)
+	}
+
+	subject := "Subject: =?UTF-8?B?" + base64.StdEncoding.EncodeToString([]byte("[VM Watchdog] db: Stopped")) + "?="
+	if !strings.Contains(server.data, subject) {
+		t.Errorf("expected an encoded subject, got:\n%s", server.data)
+	}
+	if !strings.Contains(server.data, "\r\n\r\n🚨 СБОЙ: ВМ db недоступна.\r\nСтатус: Stopped, http_check: 5*3\r\n") {
+		t.Errorf("expected a plain text body with CRLF line endings, got:\n%s", server.data)
+	}
+}
+
+func TestEmailNotifier_ReportsRejection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("554 No SMTP service here\r\n"))
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	notifier := NewEmailNotifier("127.0.0.1", port, "", "", "watchdog@example.com", []string{"ops@example.com"})
+	err = notifier.Notify(context.Background(), Notification{VMName: "db", Message: "down"})
+	if err == nil || !strings.Contains(err.Error(), "554") {
+		t.Errorf("expected the server's rejection, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package notification
+
+import "strings"
+
+// Alert actions encoded in inline keyboard callback data as "<action>:<vm>"
+const (
+	ActionStart   = "start"
+	ActionMute    = "mute"
+	ActionAck     = "ack"
+	ActionDetails = "details"
+)
+
+// InlineKeyboardButton is a button attached to a message
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// InlineKeyboardMarkup is a keyboard attached to a message
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// NewAlertKeyboard builds the action buttons attached to VM failure alerts
+func NewAlertKeyboard(vmName string) *InlineKeyboardMarkup {
+	return &InlineKeyboardMarkup{
+		InlineKeyboard: [][]InlineKeyboardButton{
+			{
+				{Text: "🚀 Запустить", CallbackData: CallbackData(ActionStart, vmName)},
+				{Text: "🔕 Тишина 1ч", CallbackData: CallbackData(ActionMute, vmName)},
+			},
+			{
+				{Text: "👀 Принять", CallbackData: CallbackData(ActionAck, vmName)},
+				{Text: "ℹ️ Подробнее", CallbackData: CallbackData(ActionDetails, vmName)},
+			},
+		},
+	}
+}
+
+// CallbackData encodes an action for a VM.
+// Telegram limits callback data to 64 bytes, which fits any sane VM name.
+func CallbackData(action, vmName string) string {
+	return action + ":" + vmName
+}
+
+// ParseCallbackData decodes data produced by CallbackData
+func ParseCallbackData(data string) (action, vmName string, ok bool) {
+	action, vmName, ok = strings.Cut(data, ":")
+	if !ok || action == "" || vmName == "" {
+		return "", "", false
+	}
+	return action, vmName, true
+}
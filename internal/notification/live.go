@@ -0,0 +1,364 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+// maxLiveChars bounds a live message so it stays under Telegram's limit of
+// 4096 characters. Markup is removed before Telegram counts, so the raw text
+// is a safe upper bound.
+const maxLiveChars = 4000
+
+// liveMessage is a Telegram message that is edited as an incident progresses
+type liveMessage struct {
+	MessageID int                   `json:"message_id"`
+	Lines     []string              `json:"lines"`
+	Keyboard  *InlineKeyboardMarkup `json:"keyboard,omitempty"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// LiveMessages keeps one Telegram message per open incident and edits it in place.
+// Message IDs are persisted to a JSON file so edits continue after a restart.
+type LiveMessages struct {
+	client *TelegramClient
+	path   string
+	maxAge time.Duration // messages not updated for longer are forgotten
+	// mu guards the maps and is never held across a Telegram call, which may
+	// wait for the chat's rate limit. Updates of one message are serialized
+	// by its incident lock instead.
+	mu        sync.Mutex
+	messages  map[string]*liveMessage
+	incidents map[string]*incidentLock
+}
+
+// incidentLock serializes updates of one incident's message
+type incidentLock struct {
+	sync.Mutex
+	refs int
+}
+
+// NewLiveMessages creates a live message store backed by the file at path.
+// Messages of incidents that saw no update for maxAge are forgotten, e.g. when
+// the VM stopped being monitored while it was down; zero means
+// DefaultOutboxMaxAge.
+func NewLiveMessages(client *TelegramClient, path string, maxAge time.Duration) (*LiveMessages, error) {
+	if maxAge <= 0 {
+		maxAge = DefaultOutboxMaxAge
+	}
+	l := &LiveMessages{
+		client:    client,
+		path:      path,
+		maxAge:    maxAge,
+		messages:  make(map[string]*liveMessage),
+		incidents: make(map[string]*incidentLock),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("failed to read live messages: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &l.messages); err != nil {
+		return nil, fmt.Errorf("failed to parse live messages: %w", err)
+	}
+
+	if n := l.prune(); n > 0 {
+		logger.Info("Forgot stale live messages",
+			"count", n,
+		)
+		l.save()
+	}
+	return l, nil
+}
+
+// Deliver posts the notification as a new live message or appends it to the
+// incident's existing message. Resolving notifications close the message.
+func (l *LiveMessages) Deliver(ctx context.Context, notif Notification) error {
+	defer l.lockIncident(notif.Incident)()
+
+	msg, exists := l.get(notif.Incident)
+	if !exists {
+		sent, err := l.client.SendMessageWithKeyboard(ctx, notif.Message, notif.Keyboard)
+		if err != nil {
+			return err
+		}
+		if notif.Resolve {
+			return nil
+		}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.messages[notif.Incident] = &liveMessage{
+			MessageID: sent.MessageID,
+			Lines:     []string{notif.Message},
+			Keyboard:  notif.Keyboard,
+			UpdatedAt: time.Now(),
+		}
+		l.save()
+		return nil
+	}
+
+	// Work on copies: a failed edit is retried and must not leave the line behind
+	lines := appendLine(msg.Lines, notif.Message)
+	keyboard := msg.Keyboard
+	if notif.Keyboard != nil {
+		keyboard = notif.Keyboard
+	}
+	if notif.Resolve {
+		keyboard = nil
+	}
+
+	messageID, err := l.edit(ctx, msg.MessageID, lines, keyboard)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if notif.Resolve {
+		delete(l.messages, notif.Incident)
+	} else {
+		current, ok := l.messages[notif.Incident]
+		if !ok {
+			// Pruned as stale while the edit was in flight
+			current = &liveMessage{}
+			l.messages[notif.Incident] = current
+		}
+		current.update(messageID, lines, keyboard)
+	}
+	l.save()
+	return nil
+}
+
+// Annotate appends a line to the live message with the given Telegram ID.
+// It returns false if the message is not tracked as a live message.
+func (l *LiveMessages) Annotate(ctx context.Context, messageID int, line string) (bool, error) {
+	incident, ok := l.find(messageID)
+	if !ok {
+		return false, nil
+	}
+	defer l.lockIncident(incident)()
+
+	// The incident may have been resolved while waiting for its lock
+	msg, ok := l.get(incident)
+	if !ok || msg.MessageID != messageID {
+		return false, nil
+	}
+
+	lines := appendLine(msg.Lines, line)
+	newID, err := l.edit(ctx, messageID, lines, msg.Keyboard)
+	if err != nil {
+		return true, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if current, ok := l.messages[incident]; ok {
+		current.update(newID, lines, msg.Keyboard)
+		l.save()
+	}
+	return true, nil
+}
+
+// get returns a copy of the incident's message
+func (l *LiveMessages) get(incident string) (liveMessage, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	msg, ok := l.messages[incident]
+	if !ok {
+		return liveMessage{}, false
+	}
+	c := *msg
+	c.Lines = slices.Clone(msg.Lines)
+	return c, true
+}
+
+// find returns the incident whose message has the given Telegram ID
+func (l *LiveMessages) find(messageID int) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for incident, msg := range l.messages {
+		if msg.MessageID == messageID {
+			return incident, true
+		}
+	}
+	return "", false
+}
+
+// lockIncident takes the incident's lock and returns the function releasing it
+func (l *LiveMessages) lockIncident(incident string) func() {
+	l.mu.Lock()
+	lock, ok := l.incidents[incident]
+	if !ok {
+		lock = &incidentLock{}
+		l.incidents[incident] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.incidents, incident)
+		}
+	}
+}
+
+// edit re-renders a live message and returns its ID. If the message was
+// deleted in the chat, a fresh message replaces it.
+func (l *LiveMessages) edit(ctx context.Context, messageID int, lines []string, keyboard *InlineKeyboardMarkup) (int, error) {
+	text := strings.Join(lines, "\n\n")
+
+	err := l.client.EditMessageText(ctx, MessageEdit{
+		MessageID: messageID,
+		Text:      text,
+		Keyboard:  keyboard,
+	})
+	var tgErr *TelegramError
+	if err == nil || errors.As(err, &tgErr) && strings.Contains(tgErr.Description, "message is not modified") {
+		return messageID, nil
+	}
+	if tgErr == nil || !strings.Contains(tgErr.Description, "message to edit not found") {
+		return 0, err
+	}
+
+	sent, err := l.client.SendMessageWithKeyboard(ctx, text, keyboard)
+	if err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// update records a successful edit
+func (msg *liveMessage) update(messageID int, lines []string, keyboard *InlineKeyboardMarkup) {
+	msg.MessageID = messageID
+	msg.Lines = lines
+	msg.Keyboard = keyboard
+	msg.UpdatedAt = time.Now()
+}
+
+// prune forgets messages that were not updated within maxAge and returns
+// how many it dropped. The caller holds mu, or owns l.
+func (l *LiveMessages) prune() int {
+	n := 0
+	for incident, msg := range l.messages {
+		if time.Since(msg.UpdatedAt) > l.maxAge {
+			delete(l.messages, incident)
+			n++
+		}
+	}
+	return n
+}
+
+// save prunes stale messages and writes the store atomically; failures are
+// logged since the messages themselves were delivered
+func (l *LiveMessages) save() {
+	l.prune()
+
+	data, err := json.MarshalIndent(l.messages, "", "  ")
+	if err != nil {
+		logger.Error("Failed to marshal live messages",
+			"error", err,
+		)
+		return
+	}
+
+	if err := writeFileAtomic(l.path, data); err != nil {
+		logger.Error("Failed to save live messages",
+			"path", l.path,
+			"error", err,
+		)
+	}
+}
+
+// appendLine adds a line, dropping the oldest updates (but never the header)
+// once the message grows past maxLiveChars. A header and update that are too
+// long on their own are cut short.
+func appendLine(lines []string, line string) []string {
+	lines = append(lines, line)
+	for len(lines) > 2 && liveLength(lines) > maxLiveChars {
+		lines = append(lines[:1], lines[2:]...)
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		excess := liveLength(lines) - maxLiveChars
+		if excess <= 0 {
+			break
+		}
+		lines[i] = truncate(lines[i], utf8.RuneCountInString(lines[i])-excess)
+	}
+	return lines
+}
+
+// liveLength is the number of characters of the rendered message
+func liveLength(lines []string) int {
+	n := 2 * (len(lines) - 1) // separators
+	for _, line := range lines {
+		n += utf8.RuneCountInString(line)
+	}
+	return n
+}
+
+// truncate shortens s to at most n characters, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	if n < 1 {
+		return ""
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// EscapeMarkdown escapes characters that have a meaning in Telegram's legacy Markdown
+func EscapeMarkdown(s string) string {
+	replacer := strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
+	return replacer.Replace(s)
+}
+
+// unescapeMarkdown undoes EscapeMarkdown for channels without Telegram's escapes
+func unescapeMarkdown(s string) string {
+	replacer := strings.NewReplacer("\\_", "_", "\\*", "*", "\\`", "`", "\\[", "[")
+	return replacer.Replace(s)
+}
+
+// plainText strips Telegram Markdown from a message for channels that show it
+// as text. Escaped characters are text and are kept.
+func plainText(s string) string {
+	replacer := strings.NewReplacer("\\_", "_", "\\*", "*", "\\`", "`", "\\[", "[", "*", "", "`", "")
+	return replacer.Replace(s)
+}
@@ -0,0 +1,271 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+// fakeBotAPI records sendMessage/editMessageText calls and hands out message IDs
+type fakeBotAPI struct {
+	mu     sync.Mutex
+	nextID int
+	calls  []string
+	texts  []string
+	// failEdits is the number of edits to answer with a server error
+	failEdits int
+	// stall, when set, holds edits until it is closed
+	stall chan struct{}
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	f.mu.Lock()
+	stall := f.stall
+	f.mu.Unlock()
+	if stall != nil && method == "editMessageText" {
+		<-stall
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, method)
+	f.texts = append(f.texts, payload["text"].(string))
+
+	if method == "editMessageText" && f.failEdits > 0 {
+		f.failEdits--
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 502, "description": "Bad Gateway"})
+		return
+	}
+
+	var result interface{} = true
+	if method == "sendMessage" {
+		f.nextID++
+		result = map[string]interface{}{"message_id": f.nextID, "chat": map[string]interface{}{"id": 1}}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
+
+func TestLiveMessages_EditsIncidentInPlace(t *testing.T) {
+	api := &fakeBotAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := NewTelegramClient("token", 1, nil)
+	client.SetAPIURL(server.URL)
+
+	path := filepath.Join(t.TempDir(), "live.json")
+	live, err := NewLiveMessages(client, path, 0)
+	if err != nil {
+		t.Fatalf("NewLiveMessages: %v", err)
+	}
+
+	ctx := context.Background()
+	deliver := func(l *LiveMessages, status types.VMStatus, text string, resolve bool) {
+		t.Helper()
+		err := l.Deliver(ctx, Notification{
+			VMName:   "db",
+			Status:   status,
+			Message:  text,
+			Incident: "db",
+			Resolve:  resolve,
+			Keyboard: NewAlertKeyboard("db"),
+		})
+		if err != nil {
+			t.Fatalf("Deliver(%s): %v", status, err)
+		}
+	}
+
+	deliver(live, types.StatusStopped, "stopped", false)
+	deliver(live, types.StatusStarting, "starting", false)
+
+	// A restart must keep editing the same message
+	restored, err := NewLiveMessages(client, path, 0)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if handled, err := restored.Annotate(ctx, 1, "acked"); !handled || err != nil {
+		t.Fatalf("Annotate() = %v, %v; want handled", handled, err)
+	}
+	deliver(restored, types.StatusRunning, "running", true)
+
+	// The incident is closed, the next failure starts a new message
+	deliver(restored, types.StatusStopped, "stopped again", false)
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	want := []string{"sendMessage", "editMessageText", "editMessageText", "editMessageText", "sendMessage"}
+	if strings.Join(api.calls, ",") != strings.Join(want, ",") {
+		t.Fatalf("calls = %v, want %v", api.calls, want)
+	}
+	if got := api.texts[3]; got != "stopped\n\nstarting\n\nacked\n\nrunning" {
+		t.Errorf("final edit text = %q", got)
+	}
+}
+
+func TestLiveMessages_RetriedEditAddsLineOnce(t *testing.T) {
+	api := &fakeBotAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := NewTelegramClient("token", 1, nil)
+	client.SetAPIURL(server.URL)
+
+	live, err := NewLiveMessages(client, filepath.Join(t.TempDir(), "live.json"), 0)
+	if err != nil {
+		t.Fatalf("NewLiveMessages: %v", err)
+	}
+
+	ctx := context.Background()
+	notif := Notification{VMName: "db", Message: "stopped", Incident: "db", Keyboard: NewAlertKeyboard("db")}
+	if err := live.Deliver(ctx, notif); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	api.mu.Lock()
+	api.failEdits = 1
+	api.mu.Unlock()
+
+	resolved := Notification{VMName: "db", Message: "running", Incident: "db", Resolve: true}
+	if err := live.Deliver(ctx, resolved); err == nil {
+		t.Fatal("expected the failed edit to be reported")
+	}
+	if err := live.Deliver(ctx, resolved); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if got := api.texts[len(api.texts)-1]; got != "stopped\n\nrunning" {
+		t.Errorf("final edit text = %q, want the update once", got)
+	}
+}
+
+func TestLiveMessages_SlowEditDoesNotBlockOtherIncidents(t *testing.T) {
+	api := &fakeBotAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := NewTelegramClient("token", 1, nil)
+	client.SetAPIURL(server.URL)
+
+	live, err := NewLiveMessages(client, filepath.Join(t.TempDir(), "live.json"), 0)
+	if err != nil {
+		t.Fatalf("NewLiveMessages: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := live.Deliver(ctx, Notification{VMName: "db", Message: "stopped", Incident: "db"}); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	stall := make(chan struct{})
+	api.mu.Lock()
+	api.stall = stall
+	api.mu.Unlock()
+
+	edited := make(chan error, 1)
+	go func() {
+		edited <- live.Deliver(ctx, Notification{VMName: "db", Message: "starting", Incident: "db"})
+	}()
+
+	// A new incident is posted while the edit of the first one hangs
+	done := make(chan error, 1)
+	go func() {
+		done <- live.Deliver(ctx, Notification{VMName: "web", Message: "stopped", Incident: "web"})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Deliver(web): %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a stalled edit blocked another incident")
+	}
+
+	close(stall)
+	if err := <-edited; err != nil {
+		t.Fatalf("Deliver(db): %v", err)
+	}
+}
+
+func TestAppendLine_KeepsHeader(t *testing.T) {
+	header := "stopped"
+	update := strings.Repeat("x", 300)
+
+	lines := []string{header}
+	for i := 0; i < 30; i++ {
+		lines = appendLine(lines, fmt.Sprintf("%02d %s", i, update))
+	}
+
+	if n := liveLength(lines); n > maxLiveChars {
+		t.Fatalf("length = %d, want at most %d", n, maxLiveChars)
+	}
+	if lines[0] != header {
+		t.Errorf("header = %q, want %q", lines[0], header)
+	}
+	if last := lines[len(lines)-1]; !strings.HasPrefix(last, "29 ") {
+		t.Errorf("last line = %q", last[:10])
+	}
+}
+
+func TestAppendLine_CutsOversizedUpdate(t *testing.T) {
+	lines := appendLine([]string{"stopped"}, strings.Repeat("й", 5000))
+
+	if len(lines) != 2 || lines[0] != "stopped" {
+		t.Fatalf("expected the header and one update, got %d lines", len(lines))
+	}
+	if n := liveLength(lines); n != maxLiveChars {
+		t.Errorf("length = %d, want %d", n, maxLiveChars)
+	}
+	if !strings.HasSuffix(lines[1], "…") {
+		t.Error("expected the cut to be marked")
+	}
+}
+
+func TestLiveMessages_ForgetsStaleIncidents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "live.json")
+	stale := map[string]liveMessage{
+		"gone":  {MessageID: 1, Lines: []string{"down"}, UpdatedAt: time.Now().Add(-2 * time.Hour)},
+		"fresh": {MessageID: 2, Lines: []string{"down"}, UpdatedAt: time.Now()},
+	}
+	data, _ := json.Marshal(stale)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := NewLiveMessages(NewTelegramClient("token", 1, nil), path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewLiveMessages: %v", err)
+	}
+	if _, ok := live.find(1); ok {
+		t.Error("expected the stale message to be forgotten on load")
+	}
+	if _, ok := live.find(2); !ok {
+		t.Error("expected the fresh message to be kept")
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(saved), "gone") {
+		t.Errorf("expected the file to drop the stale message, got %s", saved)
+	}
+}
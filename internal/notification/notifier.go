@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Notifier delivers notifications to a single destination
+type Notifier interface {
+	// Name identifies the backend in logs and configuration
+	Name() string
+	// Notify delivers one notification
+	Notify(ctx context.Context, notif Notification) error
+}
+
+// Channel routes notifications of at least MinPriority to a Notifier
+type Channel struct {
+	Notifier    Notifier
+	MinPriority Priority
+	// EscalationOnly channels receive only notifications that name them
+	EscalationOnly bool
+}
+
+// accepts reports whether the channel delivers notif. A notification that
+// names the channel is delivered regardless of its priority.
+func (ch Channel) accepts(notif Notification) bool {
+	if slices.Contains(notif.Channels, ch.Notifier.Name()) {
+		return true
+	}
+	return !ch.EscalationOnly && notif.Priority >= ch.MinPriority
+}
+
+// String returns the configuration name of the priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// ParsePriority converts a configuration name to a Priority.
+// An empty string means PriorityLow, i.e. everything is delivered.
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(s) {
+	case "", "low":
+		return PriorityLow, nil
+	case "normal":
+		return PriorityNormal, nil
+	case "critical":
+		return PriorityCritical, nil
+	default:
+		return PriorityLow, fmt.Errorf("unknown priority %q", s)
+	}
+}
+
+// TelegramNotifier posts notifications to the Telegram group
+type TelegramNotifier struct {
+	client *TelegramClient
+	live   *LiveMessages
+}
+
+// NewTelegramNotifier creates a Telegram backend. When live is non-nil,
+// notifications that belong to an incident are edited into one message.
+func NewTelegramNotifier(client *TelegramClient, live *LiveMessages) *TelegramNotifier {
+	return &TelegramNotifier{
+		client: client,
+		live:   live,
+	}
+}
+
+// Name implements Notifier
+func (t *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+// Notify implements Notifier
+func (t *TelegramNotifier) Notify(ctx context.Context, notif Notification) error {
+	// Edits do not notify anyone: escalations always get a message of their own
+	if t.live != nil && notif.Incident != "" && notif.Escalation == 0 {
+		return t.live.Deliver(ctx, notif)
+	}
+
+	text := notif.Message
+	if len(notif.Mentions) > 0 {
+		text += "\n\n" + formatMentions(notif.Mentions)
+	}
+	_, err := t.client.SendMessageWithKeyboard(ctx, text, notif.Keyboard)
+	return err
+}
+
+// formatMentions renders Telegram mentions in legacy Markdown: @usernames as
+// they are, numeric user IDs as links to the user
+func formatMentions(mentions []string) string {
+	parts := make([]string, 0, len(mentions))
+	for _, m := range mentions {
+		if strings.HasPrefix(m, "@") {
+			parts = append(parts, EscapeMarkdown(m))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("[%s](tg://user?id=%s)", m, m))
+	}
+	return strings.Join(parts, " ")
+}
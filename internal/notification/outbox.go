@@ -0,0 +1,368 @@
+package notification
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+)
+
+const (
+	// DefaultOutboxMaxAge is how long an undelivered notification is retried
+	DefaultOutboxMaxAge = 24 * time.Hour
+
+	// maxOutboxEntries bounds the entries of one channel so a long outage
+	// cannot fill the disk, or hold back the other channels
+	maxOutboxEntries = 1000
+
+	// compactAfter is the number of delivered entries after which the file is rewritten
+	compactAfter = 500
+
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 10 * time.Minute
+)
+
+// errOutboxFull is returned when a channel has maxOutboxEntries undelivered entries
+var errOutboxFull = errors.New("outbox is full")
+
+// outboxRecord is a line of the outbox file: a new entry, or the removal of
+// an entry that was delivered or expired
+type outboxRecord struct {
+	ID           int64         `json:"id"`
+	Channel      string        `json:"channel,omitempty"`
+	Created      time.Time     `json:"created,omitzero"`
+	Notification *Notification `json:"notification,omitempty"`
+	Done         bool          `json:"done,omitempty"`
+}
+
+// outboxEntry is a notification waiting for delivery to one channel
+type outboxEntry struct {
+	id       int64
+	channel  string
+	created  time.Time
+	notif    Notification
+	attempts int
+	next     time.Time // zero: due now
+	inflight bool
+}
+
+// Outbox holds notifications until each channel has delivered them. Entries
+// are appended to a JSON lines file, so notifications that are queued or
+// failing when the process stops are delivered after a restart.
+type Outbox struct {
+	path     string
+	maxAge   time.Duration
+	minDelay time.Duration
+	maxDelay time.Duration
+	mu       sync.Mutex
+	file     *os.File
+	entries  []*outboxEntry // undelivered, oldest first
+	nextID   int64
+	done     int // removals written since the file was last compacted
+	wake     chan struct{}
+	pending  map[string]int // undelivered entries per channel
+}
+
+// NewMemoryOutbox creates an outbox that does not survive a restart
+func NewMemoryOutbox() *Outbox {
+	return &Outbox{
+		maxAge:   DefaultOutboxMaxAge,
+		minDelay: minRetryDelay,
+		maxDelay: maxRetryDelay,
+		nextID:   1,
+		wake:     make(chan struct{}, 1),
+		pending:  make(map[string]int),
+	}
+}
+
+// OpenOutbox loads the outbox file at path, creating it if missing.
+// Notifications older than maxAge are given up on; zero means DefaultOutboxMaxAge.
+func OpenOutbox(path string, maxAge time.Duration) (*Outbox, error) {
+	o := NewMemoryOutbox()
+	o.path = path
+	if maxAge > 0 {
+		o.maxAge = maxAge
+	}
+
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	// Start from a file that holds only the undelivered entries
+	if err := o.compact(); err != nil {
+		return nil, err
+	}
+
+	if len(o.entries) > 0 {
+		logger.Info("📬 Undelivered notifications loaded",
+			"count", len(o.entries),
+		)
+	}
+	o.updatePending()
+	return o, nil
+}
+
+func (o *Outbox) load() error {
+	data, err := os.ReadFile(o.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec outboxRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A crash can leave the last line half-written
+			logger.Warn("Skipping unreadable outbox record",
+				"path", o.path,
+				"error", err,
+			)
+			continue
+		}
+
+		o.nextID = max(o.nextID, rec.ID+1)
+		if rec.Done {
+			o.remove(rec.ID)
+			continue
+		}
+		if rec.Notification == nil {
+			continue
+		}
+		o.entries = append(o.entries, &outboxEntry{
+			id:      rec.ID,
+			channel: rec.Channel,
+			created: rec.Created,
+			notif:   *rec.Notification,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read outbox: %w", err)
+	}
+	return nil
+}
+
+// compact rewrites the file with the undelivered entries and reopens it for appending
+func (o *Outbox) compact() error {
+	if o.path == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, e := range o.entries {
+		line, err := json.Marshal(e.record())
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox entry: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if o.file != nil {
+		o.file.Close()
+		o.file = nil
+	}
+	if err := writeFileAtomic(o.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	file, err := os.OpenFile(o.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
+	o.file = file
+	o.done = 0
+	return nil
+}
+
+// write appends a record to the file and flushes it to disk
+func (o *Outbox) write(rec outboxRecord) {
+	if o.file == nil {
+		return
+	}
+
+	line, err := json.Marshal(rec)
+	if err == nil {
+		line = append(line, '\n')
+		if _, err = o.file.Write(line); err == nil {
+			err = o.file.Sync()
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to save outbox",
+			"path", o.path,
+			"error", err,
+		)
+	}
+}
+
+func (e *outboxEntry) record() outboxRecord {
+	notif := e.notif
+	return outboxRecord{
+		ID:           e.id,
+		Channel:      e.channel,
+		Created:      e.created,
+		Notification: &notif,
+	}
+}
+
+// Add stores a notification for delivery to the named channel
+func (o *Outbox) Add(channel string, notif Notification) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.pending[channel] >= maxOutboxEntries {
+		return errOutboxFull
+	}
+
+	e := &outboxEntry{
+		id:      o.nextID,
+		channel: channel,
+		created: time.Now(),
+		notif:   notif,
+	}
+	o.nextID++
+	o.entries = append(o.entries, e)
+	o.write(e.record())
+	o.updatePending()
+	o.signal()
+	return nil
+}
+
+// claim returns an entry that is due for delivery and marks it in flight.
+// Entries for the same VM and channel are delivered in order, so a failing
+// entry holds back the later ones. Without a due entry, claim returns how
+// long until the next one is due, or a negative duration if none is waiting.
+func (o *Outbox) claim(now time.Time) (*outboxEntry, time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	wait := time.Duration(-1)
+	blocked := make(map[string]bool)
+	for _, e := range o.entries {
+		key := e.channel + "\x00" + e.notif.VMName
+		if blocked[key] {
+			continue
+		}
+		blocked[key] = true
+
+		if e.inflight {
+			continue
+		}
+		if d := e.next.Sub(now); d > 0 {
+			if wait < 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		e.inflight = true
+		// Another worker may pick up the next entry
+		o.signal()
+		return e, 0
+	}
+	return nil, wait
+}
+
+// complete removes an entry that was delivered or will not be retried
+func (o *Outbox) complete(e *outboxEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.remove(e.id)
+	o.write(outboxRecord{ID: e.id, Done: true})
+	o.done++
+	if o.done >= compactAfter && o.file != nil {
+		if err := o.compact(); err != nil {
+			logger.Error("Failed to compact outbox",
+				"path", o.path,
+				"error", err,
+			)
+		}
+	}
+	o.updatePending()
+	o.signal()
+}
+
+// retry schedules another attempt with exponential backoff, but not before
+// the wait the failed attempt asked for. It returns the number of the failed
+// attempt and the delay before the next one.
+func (o *Outbox) retry(e *outboxEntry, atLeast time.Duration) (int, time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e.attempts++
+	delay := o.minDelay
+	for i := 1; i < e.attempts && delay < o.maxDelay; i++ {
+		delay *= 2
+	}
+	delay = max(min(delay, o.maxDelay), atLeast)
+
+	e.next = time.Now().Add(delay)
+	e.inflight = false
+	return e.attempts, delay
+}
+
+// expired reports whether an entry is too old to be worth delivering
+func (o *Outbox) expired(e *outboxEntry) bool {
+	return time.Since(e.created) > o.maxAge
+}
+
+// Len returns the number of undelivered entries
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// Close closes the outbox file; undelivered entries stay in it
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
+
+func (o *Outbox) remove(id int64) {
+	for i, e := range o.entries {
+		if e.id == id {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// signal wakes an idle worker without blocking
+func (o *Outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// updatePending recounts the entries of each channel and publishes the counts
+func (o *Outbox) updatePending() {
+	// Channels keep their key at zero so the gauge drops back to 0
+	for channel := range o.pending {
+		o.pending[channel] = 0
+	}
+	for _, e := range o.entries {
+		o.pending[e.channel]++
+	}
+	for channel, n := range o.pending {
+		metrics.NotificationsPending.Set(float64(n), channel)
+	}
+}
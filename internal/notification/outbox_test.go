@@ -0,0 +1,242 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
+)
+
+// flakyNotifier fails the first failures deliveries
+type flakyNotifier struct {
+	mu        sync.Mutex
+	name      string
+	failures  int
+	calls     int
+	delivered []Notification
+}
+
+func (f *flakyNotifier) Name() string {
+	if f.name == "" {
+		return "flaky"
+	}
+	return f.name
+}
+
+func (f *flakyNotifier) Notify(_ context.Context, notif Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("service unavailable")
+	}
+	f.delivered = append(f.delivered, notif)
+	return nil
+}
+
+func (f *flakyNotifier) state() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls, len(f.delivered)
+}
+
+func TestOutbox_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	o, err := OpenOutbox(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Add("telegram", Notification{VMName: "vm-1", Message: "delivered"})
+	o.Add("telegram", Notification{VMName: "vm-2", Message: "pending", Keyboard: NewAlertKeyboard("vm-2")})
+
+	e, _ := o.claim(time.Now())
+	o.complete(e)
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err = OpenOutbox(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if o.Len() != 1 {
+		t.Fatalf("expected 1 undelivered entry after a restart, got %d", o.Len())
+	}
+	e, _ = o.claim(time.Now())
+	if e == nil || e.notif.Message != "pending" || e.notif.Keyboard == nil || e.channel != "telegram" {
+		t.Fatalf("expected the pending notification to be restored, got %+v", e)
+	}
+
+	o.Add("telegram", Notification{VMName: "vm-3"})
+	if o.entries[1].id <= e.id {
+		t.Errorf("expected IDs to continue after a restart, got %d after %d", o.entries[1].id, e.id)
+	}
+}
+
+func TestOutbox_ClaimOrder(t *testing.T) {
+	o := NewMemoryOutbox()
+	o.Add("telegram", Notification{VMName: "vm-1", Message: "first"})
+	o.Add("telegram", Notification{VMName: "vm-1", Message: "second"})
+	o.Add("telegram", Notification{VMName: "vm-2", Message: "other"})
+	o.Add("email", Notification{VMName: "vm-1", Message: "first"})
+
+	now := time.Now()
+	first, _ := o.claim(now)
+	other, _ := o.claim(now)
+	email, _ := o.claim(now)
+	if first.notif.Message != "first" || other.notif.Message != "other" || email.channel != "email" {
+		t.Fatalf("unexpected claim order: %+v, %+v, %+v", first, other, email)
+	}
+
+	// The second vm-1 message waits for the first one, even while it is retried
+	if _, delay := o.retry(first, 0); delay != minRetryDelay {
+		t.Errorf("expected the first retry after %s, got %s", minRetryDelay, delay)
+	}
+	if e, wait := o.claim(now); e != nil || wait <= 0 {
+		t.Fatalf("expected nothing due until the retry, got %+v (wait %s)", e, wait)
+	}
+	if _, delay := o.retry(first, 0); delay != 2*minRetryDelay {
+		t.Errorf("expected the delay to double, got %s", delay)
+	}
+
+	if e, _ := o.claim(first.next); e != first {
+		t.Fatalf("expected the retried entry to be due, got %+v", e)
+	}
+	o.complete(first)
+	if e, _ := o.claim(now); e == nil || e.notif.Message != "second" {
+		t.Fatalf("expected the second message after the first was delivered, got %+v", e)
+	}
+}
+
+func TestNotificationQueue_RetriesUntilDelivered(t *testing.T) {
+	notifier := &flakyNotifier{failures: 2}
+	outbox := NewMemoryOutbox()
+	outbox.minDelay = 10 * time.Millisecond
+
+	queue := NewNotificationQueue(1, Channel{Notifier: notifier})
+	queue.UseOutbox(outbox)
+	queue.Start()
+	queue.Enqueue(Notification{VMName: "vm-1", Message: "down", Priority: PriorityCritical})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, delivered := notifier.state(); delivered == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("notification was not delivered after retries")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	queue.Stop()
+
+	if calls, _ := notifier.state(); calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+	if outbox.Len() != 0 {
+		t.Errorf("expected an empty outbox, got %d entries", outbox.Len())
+	}
+}
+
+func TestNotificationQueue_ExpiresUndelivered(t *testing.T) {
+	notifier := &flakyNotifier{failures: 1}
+	outbox := NewMemoryOutbox()
+	outbox.minDelay = 10 * time.Millisecond
+	outbox.maxAge = 5 * time.Millisecond
+
+	queue := NewNotificationQueue(1, Channel{Notifier: notifier})
+	queue.UseOutbox(outbox)
+	queue.Start()
+	queue.Enqueue(Notification{VMName: "vm-1", Message: "down", Priority: PriorityCritical})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for outbox.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("notification did not expire")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	queue.Stop()
+
+	if calls, delivered := notifier.state(); calls > 1 || delivered != 0 {
+		t.Errorf("expected no retry after expiry, got %d attempts and %d deliveries", calls, delivered)
+	}
+}
+
+// rejectingNotifier always fails with a permanent error
+type rejectingNotifier struct {
+	flakyNotifier
+}
+
+func (r *rejectingNotifier) Notify(ctx context.Context, notif Notification) error {
+	r.flakyNotifier.Notify(ctx, notif)
+	return &TelegramError{Method: "sendMessage", Code: 403, Description: "Forbidden: bot was kicked from the group chat"}
+}
+
+func TestNotificationQueue_DropsRejected(t *testing.T) {
+	notifier := &rejectingNotifier{}
+	outbox := NewMemoryOutbox()
+	outbox.minDelay = 10 * time.Millisecond
+
+	queue := NewNotificationQueue(1, Channel{Notifier: notifier})
+	queue.UseOutbox(outbox)
+	queue.Start()
+	queue.Enqueue(Notification{VMName: "vm-1", Message: "down", Priority: PriorityCritical})
+	queue.Stop()
+
+	if calls, _ := notifier.state(); calls != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+	if outbox.Len() != 0 {
+		t.Errorf("expected the rejected notification to leave the outbox, got %d entries", outbox.Len())
+	}
+}
+
+func TestNotificationQueue_FullChannelKeepsOthers(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	for i := 0; i < maxOutboxEntries; i++ {
+		if err := outbox.Add("stuck", Notification{VMName: "vm-1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queue := NewNotificationQueue(1,
+		Channel{Notifier: &flakyNotifier{name: "stuck"}},
+		Channel{Notifier: &flakyNotifier{name: "healthy"}},
+	)
+	queue.UseOutbox(outbox)
+
+	dropped := metrics.NotificationsDropped.Value("stuck")
+	queue.Enqueue(Notification{VMName: "vm-2", Message: "down", Priority: PriorityCritical})
+
+	if got := metrics.NotificationsDropped.Value("stuck") - dropped; got != 1 {
+		t.Errorf("expected one drop on the full channel, got %v", got)
+	}
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	if outbox.pending["healthy"] != 1 || outbox.pending["stuck"] != maxOutboxEntries {
+		t.Errorf("expected the other channel to get the message, got pending %v", outbox.pending)
+	}
+}
+
+func TestNotificationQueue_CountsFilteredApart(t *testing.T) {
+	queue := NewNotificationQueue(1, Channel{Notifier: &flakyNotifier{}, MinPriority: PriorityCritical})
+
+	queued := metrics.NotificationsEnqueued.Value("queued")
+	filtered := metrics.NotificationsEnqueued.Value("filtered")
+	queue.Enqueue(Notification{VMName: "vm-1", Message: "started", Priority: PriorityLow})
+
+	if got := metrics.NotificationsEnqueued.Value("filtered") - filtered; got != 1 {
+		t.Errorf("expected the notification to be counted as filtered, got %v", got)
+	}
+	if got := metrics.NotificationsEnqueued.Value("queued") - queued; got != 0 {
+		t.Errorf("expected nothing counted as queued, got %v", got)
+	}
+}
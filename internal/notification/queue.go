@@ -2,21 +2,46 @@ package notification
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/fxfuren/yandex-watcher-bot/internal/metrics"
 	"github.com/fxfuren/yandex-watcher-bot/internal/types"
 	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
 )
 
 // Notification represents a message to be sent
 type Notification struct {
-	VMName   string
-	Status   types.VMStatus
-	Message  string
-	Priority Priority
+	VMName   string         `json:"vm"`
+	Status   types.VMStatus `json:"status"`
+	Message  string         `json:"message"`
+	Priority Priority       `json:"priority"`
+	// Keyboard is an optional set of action buttons attached to the message
+	Keyboard *InlineKeyboardMarkup `json:"keyboard,omitempty"`
+	// Incident groups notifications into one live message when live messages are enabled
+	Incident string `json:"incident,omitempty"`
+	// Resolve marks the last update of an incident
+	Resolve bool `json:"resolve,omitempty"`
+	// Escalation is the escalation level of a reminder about an unacknowledged
+	// incident, 0 for regular notifications
+	Escalation int `json:"escalation,omitempty"`
+	// Mentions are Telegram @usernames or numeric user IDs to notify
+	Mentions []string `json:"mentions,omitempty"`
+	// Channels names escalation-only channels that receive the notification too
+	Channels []string `json:"channels,omitempty"`
 }
 
+const (
+	// deliveryTimeout bounds a single delivery to one channel
+	deliveryTimeout = 10 * time.Second
+
+	// stopTimeout bounds how long Stop keeps delivering what is due;
+	// the rest stays in the outbox
+	stopTimeout = 10 * time.Second
+)
+
 // Priority defines the importance of a notification
 type Priority int
 
@@ -27,29 +52,45 @@ const (
 )
 
 // NotificationQueue manages a queue of notifications with deduplication
+// and fans each notification out to the configured channels. Deliveries
+// wait in an outbox and are retried until they succeed or expire.
 type NotificationQueue struct {
-	client       *TelegramClient
-	queue        chan Notification
+	channels     []Channel
+	outbox       *Outbox
+	stopping     chan struct{}
 	workers      int
 	deduplicator *Deduplicator
+	mutes        map[string]time.Time
+	mutesMu      sync.Mutex
+	busySince    []time.Time // per worker; zero while the worker is idle
+	busyMu       sync.Mutex
 	wg           sync.WaitGroup
 	ctx          context.Context
 	cancel       context.CancelFunc
 }
 
 // NewNotificationQueue creates a new notification queue
-func NewNotificationQueue(client *TelegramClient, workers int) *NotificationQueue {
+func NewNotificationQueue(workers int, channels ...Channel) *NotificationQueue {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &NotificationQueue{
-		client:       client,
-		queue:        make(chan Notification, 100),
+		channels:     channels,
+		outbox:       NewMemoryOutbox(),
+		stopping:     make(chan struct{}),
 		workers:      workers,
 		deduplicator: NewDeduplicator(5 * time.Minute),
+		mutes:        make(map[string]time.Time),
+		busySince:    make([]time.Time, workers),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
 }
 
+// UseOutbox replaces the in-memory outbox, e.g. with one backed by a file.
+// It must be called before Start; the queue closes the outbox on Stop.
+func (nq *NotificationQueue) UseOutbox(o *Outbox) {
+	nq.outbox = o
+}
+
 // Start launches the worker goroutines
 func (nq *NotificationQueue) Start() {
 	for i := 0; i < nq.workers; i++ {
@@ -58,15 +99,70 @@ func (nq *NotificationQueue) Start() {
 	}
 }
 
-// Stop gracefully stops the notification queue
+// Stop gracefully stops the notification queue. Workers deliver what is
+// due before they exit; undelivered notifications stay in the outbox.
 func (nq *NotificationQueue) Stop() {
-	close(nq.queue)
-	nq.cancel()
+	close(nq.stopping)
+	timer := time.AfterFunc(stopTimeout, nq.cancel)
 	nq.wg.Wait()
+	timer.Stop()
+	nq.cancel()
+
+	if pending := nq.outbox.Len(); pending > 0 {
+		logger.Warn("📬 Notifications left undelivered",
+			"count", pending,
+		)
+	}
+	if err := nq.outbox.Close(); err != nil {
+		logger.Error("Failed to close outbox",
+			"error", err,
+		)
+	}
+}
+
+// Mute suppresses all notifications for a VM until the given time
+func (nq *NotificationQueue) Mute(vmName string, until time.Time) {
+	nq.mutesMu.Lock()
+	defer nq.mutesMu.Unlock()
+
+	// Forget mutes that ran out, e.g. of VMs that are no longer monitored
+	now := time.Now()
+	for name, end := range nq.mutes {
+		if now.After(end) {
+			delete(nq.mutes, name)
+		}
+	}
+	nq.mutes[vmName] = until
+}
+
+// MutedUntil returns the end of the VM's mute, or zero time if it is not muted
+func (nq *NotificationQueue) MutedUntil(vmName string) time.Time {
+	nq.mutesMu.Lock()
+	defer nq.mutesMu.Unlock()
+
+	until, ok := nq.mutes[vmName]
+	if !ok {
+		return time.Time{}
+	}
+	if time.Now().After(until) {
+		delete(nq.mutes, vmName)
+		return time.Time{}
+	}
+	return until
 }
 
 // Enqueue adds a notification to the queue with deduplication
 func (nq *NotificationQueue) Enqueue(notif Notification) {
+	if until := nq.MutedUntil(notif.VMName); !until.IsZero() {
+		logger.Debug("Skipping notification for muted VM",
+			"vm", notif.VMName,
+			"status", notif.Status,
+			"muted_until", until.Format(time.RFC3339),
+		)
+		metrics.NotificationsEnqueued.Inc("muted")
+		return
+	}
+
 	// Check if this notification was recently sent
 	key := notif.VMName + ":" + string(notif.Status)
 
@@ -77,6 +173,7 @@ func (nq *NotificationQueue) Enqueue(notif Notification) {
 				"vm", notif.VMName,
 				"status", notif.Status,
 			)
+			metrics.NotificationsEnqueued.Inc("deduplicated")
 			return
 		}
 	}
@@ -84,51 +181,180 @@ func (nq *NotificationQueue) Enqueue(notif Notification) {
 	// Mark as sent
 	nq.deduplicator.Mark(key)
 
-	// Try to enqueue, don't block
-	select {
-	case nq.queue <- notif:
+	// A channel with a full outbox loses the message, the others still get it
+	accepted, queued := 0, 0
+	for _, ch := range nq.channels {
+		if !ch.accepts(notif) {
+			continue
+		}
+		accepted++
+		if err := nq.outbox.Add(ch.Notifier.Name(), notif); err != nil {
+			logger.Warn("Notification outbox full, dropping message",
+				"channel", ch.Notifier.Name(),
+				"vm", notif.VMName,
+				"status", notif.Status,
+			)
+			metrics.NotificationsDropped.Inc(ch.Notifier.Name())
+			continue
+		}
+		queued++
+	}
+
+	switch {
+	case accepted == 0:
+		// Below every channel's min_priority, or meant only for escalations
+		metrics.NotificationsEnqueued.Inc("filtered")
+	case queued == 0:
+		metrics.NotificationsEnqueued.Inc("dropped")
 	default:
-		logger.Warn("Notification queue full, dropping message",
-			"vm", notif.VMName,
-			"status", notif.Status,
-		)
+		metrics.NotificationsEnqueued.Inc("queued")
 	}
 }
 
 func (nq *NotificationQueue) worker(id int) {
 	defer nq.wg.Done()
 
-	for notif := range nq.queue {
-		// Create a timeout context for sending
-		ctx, cancel := context.WithTimeout(nq.ctx, 10*time.Second)
+	for {
+		entry, wait := nq.outbox.claim(time.Now())
+		if entry != nil {
+			nq.setBusy(id, time.Now())
+			nq.deliver(id, entry)
+			nq.setBusy(id, time.Time{})
+			continue
+		}
 
-		if err := nq.client.SendMessage(ctx, notif.Message); err != nil {
-			logger.Error("❌ Failed to send Telegram alert",
-				"worker", id,
+		// Nothing is due: sleep until the next retry or a new entry
+		var retry <-chan time.Time
+		if wait >= 0 {
+			retry = time.After(wait)
+		}
+		select {
+		case <-nq.stopping:
+			return
+		case <-nq.outbox.wake:
+		case <-retry:
+		}
+	}
+}
+
+func (nq *NotificationQueue) setBusy(id int, since time.Time) {
+	nq.busyMu.Lock()
+	defer nq.busyMu.Unlock()
+	nq.busySince[id] = since
+}
+
+// CheckWorkers reports workers that have been delivering a single notification
+// for longer than its channel timeouts allow
+func (nq *NotificationQueue) CheckWorkers() error {
+	// Every channel gets deliveryTimeout; a notifier that ignores its context
+	// is the only way to exceed this
+	limit := time.Duration(len(nq.channels))*deliveryTimeout + time.Minute
+
+	nq.busyMu.Lock()
+	defer nq.busyMu.Unlock()
+
+	var stuck []string
+	for id, since := range nq.busySince {
+		if !since.IsZero() && time.Since(since) > limit {
+			stuck = append(stuck, fmt.Sprintf("worker %d (busy for %s)", id, time.Since(since).Round(time.Second)))
+		}
+	}
+
+	if len(stuck) > 0 {
+		return fmt.Errorf("notification workers stuck: %s; %d notifications queued",
+			strings.Join(stuck, ", "), nq.outbox.Len())
+	}
+	return nil
+}
+
+// channel returns the configured channel with the given name
+func (nq *NotificationQueue) channel(name string) (Channel, bool) {
+	for _, ch := range nq.channels {
+		if ch.Notifier.Name() == name {
+			return ch, true
+		}
+	}
+	return Channel{}, false
+}
+
+func (nq *NotificationQueue) deliver(workerID int, entry *outboxEntry) {
+	notif := entry.notif
+
+	ch, ok := nq.channel(entry.channel)
+	if !ok {
+		// Left in the outbox by a run with a different configuration
+		logger.Warn("Dropping notification for a disabled channel",
+			"channel", entry.channel,
+			"vm", notif.VMName,
+		)
+		nq.outbox.complete(entry)
+		return
+	}
+	notifier := ch.Notifier
+
+	if nq.outbox.expired(entry) {
+		logger.Error("❌ Giving up on alert",
+			"channel", notifier.Name(),
+			"vm", notif.VMName,
+			"status", notif.Status,
+			"attempts", entry.attempts,
+			"queued_at", entry.created.Format(time.RFC3339),
+		)
+		metrics.NotificationsSent.Inc(notifier.Name(), metrics.OutcomeExpired)
+		nq.outbox.complete(entry)
+		return
+	}
+
+	// Create a timeout context for sending
+	ctx, cancel := context.WithTimeout(nq.ctx, deliveryTimeout)
+	defer cancel()
+
+	if err := notifier.Notify(ctx, notif); err != nil {
+		if IsPermanent(err) {
+			logger.Error("❌ Alert rejected, not retrying",
+				"worker", workerID,
+				"channel", notifier.Name(),
 				"vm", notif.VMName,
 				"status", notif.Status,
 				"error", err,
 			)
-		} else {
-			// Make it visible that alert was sent
-			var emoji string
-			switch notif.Priority {
-			case PriorityCritical:
-				emoji = "🚨"
-			case PriorityNormal:
-				emoji = "✅"
-			default:
-				emoji = "📢"
-			}
-			logger.Info(emoji+" Telegram alert sent",
-				"vm", notif.VMName,
-				"status", notif.Status,
-				"priority", notif.Priority,
-			)
+			metrics.NotificationsSent.Inc(notifier.Name(), metrics.OutcomeRejected)
+			nq.outbox.complete(entry)
+			return
 		}
 
-		cancel()
+		attempt, delay := nq.outbox.retry(entry, RetryAfter(err))
+		logger.Error("❌ Failed to send alert",
+			"worker", workerID,
+			"channel", notifier.Name(),
+			"vm", notif.VMName,
+			"status", notif.Status,
+			"attempt", attempt,
+			"retry_in", delay,
+			"error", err,
+		)
+		metrics.NotificationsSent.Inc(notifier.Name(), metrics.OutcomeError)
+		return
+	}
+	nq.outbox.complete(entry)
+	metrics.NotificationsSent.Inc(notifier.Name(), metrics.OutcomeSuccess)
+
+	// Make it visible that alert was sent
+	var emoji string
+	switch notif.Priority {
+	case PriorityCritical:
+		emoji = "🚨"
+	case PriorityNormal:
+		emoji = "✅"
+	default:
+		emoji = "📢"
 	}
+	logger.Info(emoji+" Alert sent",
+		"channel", notifier.Name(),
+		"vm", notif.VMName,
+		"status", notif.Status,
+		"priority", notif.Priority,
+	)
 }
 
 // Deduplicator prevents sending duplicate notifications within a time window
@@ -1,6 +1,8 @@
 package notification
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,7 +15,7 @@ func TestNotificationQueue_Enqueue(t *testing.T) {
 		groupChatID: 123,
 	}
 
-	queue := NewNotificationQueue(client, 1)
+	queue := NewNotificationQueue(1, Channel{Notifier: NewTelegramNotifier(client, nil)})
 
 	notif := Notification{
 		VMName:   "test-vm",
@@ -39,7 +41,7 @@ func TestNotificationQueue_CriticalBypassesDedup(t *testing.T) {
 		groupChatID: 123,
 	}
 
-	queue := NewNotificationQueue(client, 1)
+	queue := NewNotificationQueue(1, Channel{Notifier: NewTelegramNotifier(client, nil)})
 
 	notif := Notification{
 		VMName:   "test-vm",
@@ -55,6 +57,23 @@ func TestNotificationQueue_CriticalBypassesDedup(t *testing.T) {
 	queue.Stop()
 }
 
+func TestNotificationQueue_ForgetsExpiredMutes(t *testing.T) {
+	nq := NewNotificationQueue(1)
+	nq.Mute("gone", time.Now().Add(-time.Minute))
+	nq.Mute("expired", time.Now().Add(-time.Second))
+
+	if !nq.MutedUntil("expired").IsZero() {
+		t.Error("expected an expired mute to be ignored")
+	}
+	nq.Mute("vm-1", time.Now().Add(time.Hour))
+
+	nq.mutesMu.Lock()
+	defer nq.mutesMu.Unlock()
+	if len(nq.mutes) != 1 {
+		t.Errorf("expected only the active mute to be kept, got %v", nq.mutes)
+	}
+}
+
 func TestDeduplicator(t *testing.T) {
 	window := 100 * time.Millisecond
 	dedup := NewDeduplicator(window)
@@ -121,3 +140,93 @@ func BenchmarkDeduplicator_Mark(b *testing.B) {
 		dedup.Mark("test-key")
 	}
 }
+
+// recordingNotifier collects delivered notifications
+type recordingNotifier struct {
+	name string
+	mu   sync.Mutex
+	got  []Notification
+}
+
+func (r *recordingNotifier) Name() string { return r.name }
+
+func (r *recordingNotifier) Notify(ctx context.Context, notif Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.got = append(r.got, notif)
+	return nil
+}
+
+func (r *recordingNotifier) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.got)
+}
+
+func TestNotificationQueue_FanOutRespectsMinPriority(t *testing.T) {
+	all := &recordingNotifier{name: "all"}
+	critical := &recordingNotifier{name: "critical"}
+
+	queue := NewNotificationQueue(2,
+		Channel{Notifier: all, MinPriority: PriorityLow},
+		Channel{Notifier: critical, MinPriority: PriorityCritical},
+	)
+	queue.Start()
+
+	queue.Enqueue(Notification{VMName: "a", Status: types.StatusStarting, Priority: PriorityNormal})
+	queue.Enqueue(Notification{VMName: "b", Status: types.StatusStopped, Priority: PriorityCritical})
+	queue.Stop()
+
+	if got := all.count(); got != 2 {
+		t.Errorf("low channel received %d notifications, want 2", got)
+	}
+	if got := critical.count(); got != 1 {
+		t.Errorf("critical channel received %d notifications, want 1", got)
+	}
+}
+
+func TestNotificationQueue_CheckWorkers(t *testing.T) {
+	queue := NewNotificationQueue(2, Channel{Notifier: &recordingNotifier{name: "webhook"}})
+	defer queue.Stop()
+
+	if err := queue.CheckWorkers(); err != nil {
+		t.Fatalf("expected idle workers to be healthy, got %v", err)
+	}
+
+	// A delivery that is still within its timeouts is fine
+	queue.setBusy(0, time.Now().Add(-5*time.Second))
+	if err := queue.CheckWorkers(); err != nil {
+		t.Fatalf("expected busy worker to be healthy, got %v", err)
+	}
+
+	queue.setBusy(1, time.Now().Add(-10*time.Minute))
+	if err := queue.CheckWorkers(); err == nil {
+		t.Fatal("expected stuck worker to be reported")
+	}
+}
+
+func TestChannel_Accepts(t *testing.T) {
+	email := Channel{Notifier: NewEmailNotifier("", 0, "", "", "", nil), EscalationOnly: true}
+	telegram := Channel{Notifier: NewTelegramNotifier(&TelegramClient{}, nil), MinPriority: PriorityCritical}
+
+	regular := Notification{Priority: PriorityNormal}
+	escalation := Notification{Priority: PriorityCritical, Escalation: 1, Channels: []string{"email"}}
+
+	if email.accepts(regular) || telegram.accepts(regular) {
+		t.Error("expected a normal notification to reach neither channel")
+	}
+	if !email.accepts(escalation) || !telegram.accepts(escalation) {
+		t.Error("expected an escalation naming email to reach both channels")
+	}
+	if email.accepts(Notification{Priority: PriorityCritical}) {
+		t.Error("expected an escalation-only channel to skip regular notifications")
+	}
+}
+
+func TestFormatMentions(t *testing.T) {
+	got := formatMentions([]string{"@on_call", "12345"})
+	want := `@on\_call [12345](tg://user?id=12345)`
+	if got != want {
+		t.Errorf("formatMentions = %q, want %q", got, want)
+	}
+}
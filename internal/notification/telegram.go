@@ -4,17 +4,38 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/pkg/logger"
+	"golang.org/x/time/rate"
+)
+
+// DefaultTelegramAPIURL is the base URL of the public Telegram Bot API
+const DefaultTelegramAPIURL = "https://api.telegram.org"
+
+// Telegram allows bots about 20 messages per minute in a group. Edits count
+// too. The burst lets the few messages of a new incident go out at once.
+const (
+	chatMessageInterval = 3 * time.Second
+	chatMessageBurst    = 10
 )
 
 // TelegramClient handles sending notifications via Telegram
 type TelegramClient struct {
 	botToken    string
-	groupChatID int64
 	topicID     *int
+	apiURL      string
 	httpClient  *http.Client
+	pollClient  *http.Client
+	chatLimiter *rate.Limiter
+	mu          sync.Mutex
+	groupChatID int64     // changes when the group is migrated to a supergroup
+	holdUntil   time.Time // end of the retry_after of the last 429 for the group
 }
 
 // NewTelegramClient creates a new Telegram client
@@ -23,18 +44,173 @@ func NewTelegramClient(botToken string, groupChatID int64, topicID *int) *Telegr
 		botToken:    botToken,
 		groupChatID: groupChatID,
 		topicID:     topicID,
+		apiURL:      DefaultTelegramAPIURL,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		// Long polling requests are bounded by their context instead
+		pollClient:  &http.Client{},
+		chatLimiter: rate.NewLimiter(rate.Every(chatMessageInterval), chatMessageBurst),
+	}
+}
+
+// SetAPIURL overrides the Bot API base URL (used to point the client at a local server)
+func (t *TelegramClient) SetAPIURL(apiURL string) {
+	t.apiURL = apiURL
+}
+
+// GroupChatID returns the chat the client is bound to
+func (t *TelegramClient) GroupChatID() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.groupChatID
+}
+
+// User is a Telegram user or bot
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// DisplayName returns @username if set, otherwise the user's full name
+func (u *User) DisplayName() string {
+	if u == nil {
+		return "unknown"
 	}
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	if u.LastName != "" {
+		return u.FirstName + " " + u.LastName
+	}
+	return u.FirstName
+}
+
+// Chat is a Telegram chat
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// Message is a Telegram message
+type Message struct {
+	MessageID       int                   `json:"message_id"`
+	MessageThreadID int                   `json:"message_thread_id,omitempty"`
+	From            *User                 `json:"from,omitempty"`
+	Chat            Chat                  `json:"chat"`
+	Date            int64                 `json:"date"`
+	Text            string                `json:"text,omitempty"`
+	Entities        json.RawMessage       `json:"entities,omitempty"`
+	ReplyMarkup     *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// CallbackQuery is sent when a user presses an inline keyboard button
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    *User    `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// Update is a single entry returned by getUpdates
+type Update struct {
+	UpdateID      int            `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+// MessageEdit describes a replacement for the text of a sent message
+type MessageEdit struct {
+	MessageID int
+	Text      string
+	// Entities carries formatting of Text as received from Telegram.
+	// When nil, Text is parsed as Markdown.
+	Entities json.RawMessage
+	Keyboard *InlineKeyboardMarkup
+}
+
+// apiResponse is the envelope every Bot API method responds with
+type apiResponse struct {
+	OK          bool               `json:"ok"`
+	Result      json.RawMessage    `json:"result"`
+	ErrorCode   int                `json:"error_code"`
+	Description string             `json:"description"`
+	Parameters  ResponseParameters `json:"parameters"`
+}
+
+// ResponseParameters explain why a request failed and how to repeat it
+type ResponseParameters struct {
+	// MigrateToChatID is the new ID of a group that became a supergroup
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+	// RetryAfter is the number of seconds to wait after 429 Too Many Requests
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// TelegramError is an error response of the Bot API
+type TelegramError struct {
+	Method      string
+	Code        int
+	Description string
+	Parameters  ResponseParameters
+}
+
+func (e *TelegramError) Error() string {
+	return fmt.Sprintf("telegram API %s failed (%d): %s", e.Method, e.Code, e.Description)
+}
+
+// RetryAfter is how long Telegram asked to wait before the next request
+func (e *TelegramError) RetryAfter() time.Duration {
+	return time.Duration(e.Parameters.RetryAfter) * time.Second
+}
+
+// Permanent reports whether repeating the request cannot succeed: the bot was
+// blocked or kicked, the chat does not exist or the request itself is invalid.
+// Rate limits, server errors and group migrations are worth retrying.
+func (e *TelegramError) Permanent() bool {
+	if e.Parameters.MigrateToChatID != 0 {
+		return false
+	}
+	switch e.Code {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+// entityParseError reports whether Telegram rejected the formatting of the text
+func (e *TelegramError) entityParseError() bool {
+	return e.Code == http.StatusBadRequest && strings.Contains(e.Description, "can't parse entities")
+}
+
+// IsPermanent reports whether err says that retrying the delivery cannot help
+func IsPermanent(err error) bool {
+	var p interface{ Permanent() bool }
+	return errors.As(err, &p) && p.Permanent()
+}
+
+// RetryAfter returns the wait err asks for before the next attempt, or zero
+func RetryAfter(err error) time.Duration {
+	var r interface{ RetryAfter() time.Duration }
+	if errors.As(err, &r) {
+		return r.RetryAfter()
+	}
+	return 0
 }
 
 // SendMessage sends a message to the configured group chat
 func (t *TelegramClient) SendMessage(ctx context.Context, message string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.botToken)
+	_, err := t.SendMessageWithKeyboard(ctx, message, nil)
+	return err
+}
 
+// SendMessageWithKeyboard sends a message with an optional inline keyboard
+// and returns the message as stored by Telegram
+func (t *TelegramClient) SendMessageWithKeyboard(ctx context.Context, message string, keyboard *InlineKeyboardMarkup) (*Message, error) {
 	payload := map[string]interface{}{
-		"chat_id":    t.groupChatID,
 		"text":       message,
 		"parse_mode": "Markdown",
 	}
@@ -43,6 +219,171 @@ func (t *TelegramClient) SendMessage(ctx context.Context, message string) error
 		payload["message_thread_id"] = *t.topicID
 	}
 
+	if keyboard != nil {
+		payload["reply_markup"] = keyboard
+	}
+
+	var sent Message
+	if err := t.callChat(ctx, "sendMessage", payload, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// EditMessageText replaces the text (and keyboard) of a message in the group chat
+func (t *TelegramClient) EditMessageText(ctx context.Context, edit MessageEdit) error {
+	payload := map[string]interface{}{
+		"message_id": edit.MessageID,
+		"text":       edit.Text,
+	}
+
+	if edit.Entities != nil {
+		payload["entities"] = edit.Entities
+	} else {
+		payload["parse_mode"] = "Markdown"
+	}
+
+	if edit.Keyboard != nil {
+		payload["reply_markup"] = edit.Keyboard
+	}
+
+	return t.callChat(ctx, "editMessageText", payload, nil)
+}
+
+// AnswerCallbackQuery acknowledges a button press, optionally showing text to the user
+func (t *TelegramClient) AnswerCallbackQuery(ctx context.Context, callbackID, text string, showAlert bool) error {
+	payload := map[string]interface{}{
+		"callback_query_id": callbackID,
+	}
+
+	if text != "" {
+		payload["text"] = text
+		payload["show_alert"] = showAlert
+	}
+
+	return t.call(ctx, t.httpClient, "answerCallbackQuery", payload, nil)
+}
+
+// GetUpdates long-polls Telegram for updates with update_id >= offset.
+// The call blocks for up to timeout when there is nothing new.
+func (t *TelegramClient) GetUpdates(ctx context.Context, offset int, timeout time.Duration) ([]Update, error) {
+	// Leave headroom over the server-side poll timeout for network latency
+	ctx, cancel := context.WithTimeout(ctx, timeout+10*time.Second)
+	defer cancel()
+
+	payload := map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message", "callback_query"},
+	}
+
+	var updates []Update
+	if err := t.call(ctx, t.pollClient, "getUpdates", payload, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// maxChatRetries bounds how often callChat repeats a request after an error it can fix
+const maxChatRetries = 2
+
+// callChat invokes a Bot API method that posts to the group chat. It keeps to
+// the group's rate limit, waits out a 429 if the context allows it, follows
+// the group when Telegram reports that it became a supergroup and sends text
+// it cannot parse as Markdown as plain text.
+func (t *TelegramClient) callChat(ctx context.Context, method string, payload map[string]interface{}, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		if err := t.waitChat(ctx, method); err != nil {
+			return err
+		}
+
+		payload["chat_id"] = t.GroupChatID()
+		err := t.call(ctx, t.httpClient, method, payload, result)
+
+		var tgErr *TelegramError
+		if attempt >= maxChatRetries || !errors.As(err, &tgErr) {
+			return err
+		}
+		switch {
+		case tgErr.Parameters.MigrateToChatID != 0:
+			t.migrate(tgErr.Parameters.MigrateToChatID)
+		case tgErr.Code == http.StatusTooManyRequests:
+			t.hold(tgErr.RetryAfter())
+		case tgErr.entityParseError() && payload["parse_mode"] != nil:
+			// Unescaped text must not cost the alert: lose the formatting instead
+			logger.Warn("⚠️ Telegram could not parse the message as Markdown, resending as plain text",
+				"method", method,
+				"error", tgErr.Description,
+			)
+			delete(payload, "parse_mode")
+		default:
+			return err
+		}
+	}
+}
+
+// waitChat blocks until the group may receive another message. A wait that
+// would outlast ctx fails at once with the time left to wait.
+func (t *TelegramClient) waitChat(ctx context.Context, method string) error {
+	t.mu.Lock()
+	wait := time.Until(t.holdUntil)
+	t.mu.Unlock()
+
+	if wait > 0 {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return &TelegramError{
+				Method:      method,
+				Code:        http.StatusTooManyRequests,
+				Description: "Too Many Requests: waiting for the chat's retry_after",
+				Parameters:  ResponseParameters{RetryAfter: int(wait.Seconds()) + 1},
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := t.chatLimiter.Wait(ctx); err != nil {
+		return fmt.Errorf("telegram chat rate limit: %w", err)
+	}
+	return nil
+}
+
+// hold stops messages to the group for the wait Telegram asked for
+func (t *TelegramClient) hold(wait time.Duration) {
+	logger.Warn("⏳ Telegram rate limit hit, holding messages",
+		"retry_after", wait,
+	)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(wait); until.After(t.holdUntil) {
+		t.holdUntil = until
+	}
+}
+
+// migrate switches to the supergroup an upgraded group became
+func (t *TelegramClient) migrate(chatID int64) {
+	t.mu.Lock()
+	old := t.groupChatID
+	t.groupChatID = chatID
+	t.mu.Unlock()
+
+	logger.Warn("⚠️ Telegram group was migrated to a supergroup, update GROUP_CHAT_ID",
+		"old_chat_id", old,
+		"new_chat_id", chatID,
+	)
+}
+
+// call invokes a Bot API method and decodes its result into result (if non-nil)
+func (t *TelegramClient) call(ctx context.Context, httpClient *http.Client, method string, payload interface{}, result interface{}) error {
+	url := fmt.Sprintf("%s/bot%s/%s", t.apiURL, t.botToken, method)
+
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
@@ -55,14 +396,30 @@ func (t *TelegramClient) SendMessage(ctx context.Context, message string) error
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := t.httpClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram API returned status %d", resp.StatusCode)
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("telegram API returned status %d: failed to decode response: %w", resp.StatusCode, err)
+	}
+
+	if !apiResp.OK {
+		return &TelegramError{
+			Method:      method,
+			Code:        apiResp.ErrorCode,
+			Description: apiResp.Description,
+			Parameters:  apiResp.Parameters,
+		}
+	}
+
+	if result != nil && len(apiResp.Result) > 0 {
+		if err := json.Unmarshal(apiResp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
 	}
 
 	return nil
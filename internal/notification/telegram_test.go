@@ -0,0 +1,164 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedBotAPI answers sendMessage with the queued error responses, then with success
+type scriptedBotAPI struct {
+	mu         sync.Mutex
+	errors     []map[string]interface{}
+	chatIDs    []int64
+	parseModes []string
+}
+
+func (s *scriptedBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		ChatID    int64  `json:"chat_id"`
+		ParseMode string `json:"parse_mode"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chatIDs = append(s.chatIDs, payload.ChatID)
+	s.parseModes = append(s.parseModes, payload.ParseMode)
+
+	if len(s.errors) > 0 {
+		resp := s.errors[0]
+		s.errors = s.errors[1:]
+		resp["ok"] = false
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"result": map[string]interface{}{"message_id": 1, "chat": map[string]interface{}{"id": payload.ChatID}},
+	})
+}
+
+func (s *scriptedBotAPI) calls() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.chatIDs...)
+}
+
+func tooManyRequests(seconds int) map[string]interface{} {
+	return map[string]interface{}{
+		"error_code":  429,
+		"description": "Too Many Requests: retry after 1",
+		"parameters":  map[string]interface{}{"retry_after": seconds},
+	}
+}
+
+func newScriptedClient(t *testing.T, api *scriptedBotAPI) *TelegramClient {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	client := NewTelegramClient("token", -1, nil)
+	client.SetAPIURL(server.URL)
+	return client
+}
+
+func TestTelegramClient_HonoursRetryAfter(t *testing.T) {
+	api := &scriptedBotAPI{errors: []map[string]interface{}{tooManyRequests(1)}}
+	client := newScriptedClient(t, api)
+
+	start := time.Now()
+	if err := client.SendMessage(context.Background(), "hello"); err != nil {
+		t.Fatalf("expected the message to be sent after waiting, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait for retry_after, resent after %s", elapsed)
+	}
+	if n := len(api.calls()); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestTelegramClient_RetryAfterBeyondDeadline(t *testing.T) {
+	api := &scriptedBotAPI{errors: []map[string]interface{}{tooManyRequests(30)}}
+	client := newScriptedClient(t, api)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := client.SendMessage(ctx, "hello")
+	if err == nil || IsPermanent(err) || RetryAfter(err) < 29*time.Second {
+		t.Fatalf("expected a transient error asking to wait 30s, got %v (retry after %s)", err, RetryAfter(err))
+	}
+
+	// Later messages wait for the hold instead of hitting Telegram again
+	err = client.SendMessage(ctx, "again")
+	if RetryAfter(err) == 0 || len(api.calls()) != 1 {
+		t.Errorf("expected the hold to apply without a request, got %v after %d requests", err, len(api.calls()))
+	}
+}
+
+func TestTelegramClient_FollowsMigration(t *testing.T) {
+	api := &scriptedBotAPI{errors: []map[string]interface{}{{
+		"error_code":  400,
+		"description": "Bad Request: group chat was upgraded to a supergroup chat",
+		"parameters":  map[string]interface{}{"migrate_to_chat_id": -1001234},
+	}}}
+	client := newScriptedClient(t, api)
+
+	if err := client.SendMessage(context.Background(), "hello"); err != nil {
+		t.Fatalf("expected the message to reach the supergroup, got %v", err)
+	}
+	if got := api.calls(); len(got) != 2 || got[0] != -1 || got[1] != -1001234 {
+		t.Errorf("expected a resend to the supergroup, got chat IDs %v", got)
+	}
+	if client.GroupChatID() != -1001234 {
+		t.Errorf("expected the client to switch chats, got %d", client.GroupChatID())
+	}
+}
+
+func TestTelegramClient_FallsBackToPlainText(t *testing.T) {
+	api := &scriptedBotAPI{errors: []map[string]interface{}{{
+		"error_code":  400,
+		"description": "Bad Request: can't parse entities: Can't find end of the entity starting at byte offset 42",
+	}}}
+	client := newScriptedClient(t, api)
+
+	if err := client.SendMessage(context.Background(), "http /health_check: 503"); err != nil {
+		t.Fatalf("expected the message to be sent as plain text, got %v", err)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.parseModes) != 2 || api.parseModes[0] != "Markdown" || api.parseModes[1] != "" {
+		t.Errorf("expected a Markdown attempt and a plain resend, got parse modes %q", api.parseModes)
+	}
+}
+
+func TestTelegramError_Permanent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"bot kicked", &TelegramError{Code: 403, Description: "Forbidden: bot was kicked from the supergroup chat"}, true},
+		{"chat not found", &TelegramError{Code: 400, Description: "Bad Request: chat not found"}, true},
+		{"unauthorized", &TelegramError{Code: 401, Description: "Unauthorized"}, true},
+		{"rate limited", &TelegramError{Code: 429, Parameters: ResponseParameters{RetryAfter: 5}}, false},
+		{"server error", &TelegramError{Code: 502, Description: "Bad Gateway"}, false},
+		{"migrated", &TelegramError{Code: 400, Parameters: ResponseParameters{MigrateToChatID: -100}}, false},
+		{"network", errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPermanent(tt.err); got != tt.want {
+				t.Errorf("IsPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
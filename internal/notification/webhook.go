@@ -0,0 +1,127 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier posts notifications as JSON to an arbitrary HTTP endpoint
+type WebhookNotifier struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// WebhookPayload is the JSON body sent by WebhookNotifier
+type WebhookPayload struct {
+	VM         string    `json:"vm"`
+	Status     string    `json:"status"`
+	Message    string    `json:"message"`
+	Priority   string    `json:"priority"`
+	Incident   string    `json:"incident,omitempty"`
+	Resolved   bool      `json:"resolved"`
+	Escalation int       `json:"escalation,omitempty"`
+	Mentions   []string  `json:"mentions,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// NewWebhookNotifier creates a generic JSON webhook backend
+func NewWebhookNotifier(url string, headers map[string]string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:     url,
+		headers: headers,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Name implements Notifier
+func (w *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify implements Notifier. The message is sent as plain text, without
+// Telegram Markdown.
+func (w *WebhookNotifier) Notify(ctx context.Context, notif Notification) error {
+	payload := WebhookPayload{
+		VM:         notif.VMName,
+		Status:     string(notif.Status),
+		Message:    plainText(notif.Message),
+		Priority:   notif.Priority.String(),
+		Incident:   notif.Incident,
+		Resolved:   notif.Resolve,
+		Escalation: notif.Escalation,
+		Mentions:   notif.Mentions,
+		Timestamp:  time.Now().UTC(),
+	}
+
+	return postJSON(ctx, w.httpClient, w.url, w.headers, payload)
+}
+
+// SlackNotifier posts notifications to a Slack-compatible incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// NewSlackNotifier creates a Slack incoming webhook backend
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Name implements Notifier
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// Notify implements Notifier.
+// Messages use *bold* which Slack's mrkdwn renders the same way as Telegram,
+// but mrkdwn has no backslash escapes.
+func (s *SlackNotifier) Notify(ctx context.Context, notif Notification) error {
+	payload := map[string]string{
+		"text": unescapeMarkdown(notif.Message),
+	}
+
+	return postJSON(ctx, s.httpClient, s.webhookURL, nil, payload)
+}
+
+func postJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, payload interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
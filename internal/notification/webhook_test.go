@@ -0,0 +1,56 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestWebhookNotifier_PostsJSON(t *testing.T) {
+	var (
+		got    WebhookPayload
+		header string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, map[string]string{"Authorization": "Bearer x"})
+	err := notifier.Notify(context.Background(), Notification{
+		VMName:   "db",
+		Status:   types.StatusStopped,
+		Message:  "🚨 ВМ *db* недоступна: " + EscapeMarkdown("http_check: 5*3"),
+		Priority: PriorityCritical,
+	})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if got.VM != "db" || got.Status != "Stopped" || got.Priority != "critical" {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+	if got.Message != "🚨 ВМ db недоступна: http_check: 5*3" {
+		t.Errorf("expected a plain text message, got %q", got.Message)
+	}
+	if header != "Bearer x" {
+		t.Errorf("custom header not sent, got %q", header)
+	}
+}
+
+func TestSlackNotifier_ReportsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewSlackNotifier(server.URL).Notify(context.Background(), Notification{Message: "x"})
+	if err == nil {
+		t.Fatal("expected error for 403 response")
+	}
+}
@@ -0,0 +1,217 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds a single probe when no timeout is configured
+	DefaultTimeout = 5 * time.Second
+	// IPPlaceholder in a target is replaced with the VM's current IP
+	IPPlaceholder = "{ip}"
+	// maxBodySize is how much of an HTTP response is searched for the expected body
+	maxBodySize = 1 << 20
+)
+
+// Kind is the protocol a probe speaks
+type Kind string
+
+const (
+	KindTCP  Kind = "tcp"
+	KindHTTP Kind = "http"
+)
+
+// Spec describes a probe
+type Spec struct {
+	Name string
+	Kind Kind
+	// Target is host:port for TCP and a URL for HTTP; it may contain {ip}
+	Target string
+	// ExpectStatus is the required HTTP status (default: any 2xx)
+	ExpectStatus int
+	// ExpectBody must occur in the HTTP response body when set
+	ExpectBody string
+	// MaxLatency fails a probe that succeeds too slowly when set
+	MaxLatency time.Duration
+	Timeout    time.Duration
+	// Insecure skips TLS certificate verification
+	Insecure bool
+	// FailureThreshold is how many runs in a row must fail before the probe
+	// counts as down (default 1)
+	FailureThreshold int
+}
+
+// Probe checks that a service on a VM responds
+type Probe struct {
+	spec   Spec
+	client *http.Client
+}
+
+// Result is the outcome of one probe run
+type Result struct {
+	Probe   string
+	OK      bool
+	Latency time.Duration
+	Err     error
+}
+
+// New validates spec and creates a probe
+func New(spec Spec) (*Probe, error) {
+	if spec.Target == "" {
+		return nil, fmt.Errorf("target is required")
+	}
+	if spec.Timeout == 0 {
+		spec.Timeout = DefaultTimeout
+	}
+	if spec.Timeout < 0 || spec.MaxLatency < 0 {
+		return nil, fmt.Errorf("timeout and max_latency must be positive")
+	}
+	if spec.FailureThreshold == 0 {
+		spec.FailureThreshold = 1
+	}
+	if spec.FailureThreshold < 0 {
+		return nil, fmt.Errorf("failure_threshold must be positive")
+	}
+	if spec.Name == "" {
+		spec.Name = string(spec.Kind) + " " + spec.Target
+	}
+
+	p := &Probe{spec: spec}
+	switch spec.Kind {
+	case KindTCP:
+		if _, _, err := net.SplitHostPort(spec.Target); err != nil {
+			return nil, fmt.Errorf("tcp target must be host:port: %w", err)
+		}
+		if spec.ExpectStatus != 0 || spec.ExpectBody != "" {
+			return nil, fmt.Errorf("expect_status and expect_body only apply to http probes")
+		}
+
+	case KindHTTP:
+		if !strings.HasPrefix(spec.Target, "http://") && !strings.HasPrefix(spec.Target, "https://") {
+			return nil, fmt.Errorf("http target must be an http:// or https:// URL")
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// Every run checks that the service still accepts connections
+		transport.DisableKeepAlives = true
+		if spec.Insecure {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		p.client = &http.Client{
+			Transport: transport,
+			// Redirects are reported as they are, so expect_status can match a 3xx
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown probe kind %q", spec.Kind)
+	}
+	return p, nil
+}
+
+// Name returns the probe's name
+func (p *Probe) Name() string {
+	return p.spec.Name
+}
+
+// FailureThreshold returns how many runs in a row must fail before the probe counts as down
+func (p *Probe) FailureThreshold() int {
+	return p.spec.FailureThreshold
+}
+
+// Check runs the probe against a VM with the given IP
+func (p *Probe) Check(ctx context.Context, ip string) Result {
+	result := Result{Probe: p.spec.Name}
+
+	target := p.spec.Target
+	if strings.Contains(target, IPPlaceholder) {
+		if ip == "" {
+			result.Err = fmt.Errorf("VM IP is unknown")
+			return result
+		}
+		target = strings.ReplaceAll(target, IPPlaceholder, ip)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.spec.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	if p.spec.Kind == KindTCP {
+		result.Err = p.checkTCP(ctx, target)
+	} else {
+		result.Err = p.checkHTTP(ctx, target)
+	}
+	result.Latency = time.Since(start)
+
+	if result.Err == nil && p.spec.MaxLatency > 0 && result.Latency > p.spec.MaxLatency {
+		result.Err = fmt.Errorf("too slow: %s > %s", result.Latency.Round(time.Millisecond), p.spec.MaxLatency)
+	}
+	result.OK = result.Err == nil
+	return result
+}
+
+func (p *Probe) checkTCP(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (p *Probe) checkHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "yandex-watcher-bot")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if want := p.spec.ExpectStatus; want != 0 {
+		if resp.StatusCode != want {
+			return fmt.Errorf("status %d, want %d", resp.StatusCode, want)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if p.spec.ExpectBody != "" && !strings.Contains(string(body), p.spec.ExpectBody) {
+		return fmt.Errorf("body does not contain %q", p.spec.ExpectBody)
+	}
+	return nil
+}
+
+// CheckAll runs probes concurrently and returns their results in order
+func CheckAll(ctx context.Context, probes []*Probe, ip string) []Result {
+	results := make([]Result, len(probes))
+
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p *Probe) {
+			defer wg.Done()
+			results[i] = p.Check(ctx, ip)
+		}(i, p)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,165 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTCPProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	p, err := New(Spec{Kind: KindTCP, Target: "{ip}:" + port, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := p.Check(context.Background(), "127.0.0.1"); !r.OK {
+		t.Errorf("expected open port to pass, got %v", r.Err)
+	}
+	if r := p.Check(context.Background(), ""); r.OK || !strings.Contains(r.Err.Error(), "IP is unknown") {
+		t.Errorf("expected unknown IP to fail, got %+v", r)
+	}
+
+	ln.Close()
+	if r := p.Check(context.Background(), "127.0.0.1"); r.OK {
+		t.Error("expected closed port to fail")
+	}
+}
+
+func TestHTTPProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status":"ok"}`))
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/moved":
+			http.Redirect(w, r, "/health", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		spec Spec
+		ok   bool
+		err  string
+	}{
+		{"healthy", Spec{Target: server.URL + "/health"}, true, ""},
+		{"body", Spec{Target: server.URL + "/health", ExpectBody: `"ok"`}, true, ""},
+		{"wrong body", Spec{Target: server.URL + "/health", ExpectBody: "ready"}, false, "body"},
+		{"bad status", Spec{Target: server.URL + "/broken"}, false, "status 502"},
+		{"expected status", Spec{Target: server.URL + "/broken", ExpectStatus: 502}, true, ""},
+		{"redirect not followed", Spec{Target: server.URL + "/moved", ExpectStatus: 302}, true, ""},
+		{"too slow", Spec{Target: server.URL + "/slow", MaxLatency: 10 * time.Millisecond}, false, "too slow"},
+		{"timeout", Spec{Target: server.URL + "/slow", Timeout: 10 * time.Millisecond}, false, "deadline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.spec.Kind = KindHTTP
+			p, err := New(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := p.Check(context.Background(), "")
+			if r.OK != tt.ok {
+				t.Fatalf("OK = %v, want %v (err: %v)", r.OK, tt.ok, r.Err)
+			}
+			if tt.err != "" && !strings.Contains(r.Err.Error(), tt.err) {
+				t.Errorf("expected error containing %q, got %v", tt.err, r.Err)
+			}
+		})
+	}
+}
+
+func TestHTTPProbe_Insecure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	strict, _ := New(Spec{Kind: KindHTTP, Target: server.URL})
+	if r := strict.Check(context.Background(), ""); r.OK {
+		t.Error("expected self-signed certificate to fail verification")
+	}
+
+	insecure, _ := New(Spec{Kind: KindHTTP, Target: server.URL, Insecure: true})
+	if r := insecure.Check(context.Background(), ""); !r.OK {
+		t.Errorf("expected insecure probe to pass, got %v", r.Err)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []Spec{
+		{Kind: KindTCP},
+		{Kind: KindTCP, Target: "no-port"},
+		{Kind: KindTCP, Target: "{ip}:22", ExpectStatus: 200},
+		{Kind: KindHTTP, Target: "{ip}/health"},
+		{Kind: "udp", Target: "{ip}:53"},
+		{Kind: KindHTTP, Target: "http://{ip}", Timeout: -time.Second},
+		{Kind: KindTCP, Target: "{ip}:22", FailureThreshold: -1},
+	}
+
+	for _, spec := range tests {
+		if _, err := New(spec); err == nil {
+			t.Errorf("expected error for %+v", spec)
+		}
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	open, _ := New(Spec{Name: "open", Kind: KindTCP, Target: ln.Addr().String()})
+	closed, _ := New(Spec{Name: "closed", Kind: KindTCP, Target: "127.0.0.1:1", Timeout: time.Second})
+
+	results := CheckAll(context.Background(), []*Probe{open, closed}, "")
+	if len(results) != 2 || results[0].Probe != "open" || !results[0].OK || results[1].OK {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestRequirement(t *testing.T) {
+	tests := []struct {
+		in            string
+		passed, total int
+		met           bool
+	}{
+		{"", 2, 3, false},
+		{"all", 3, 3, true},
+		{"ANY", 1, 3, true},
+		{"any", 0, 3, false},
+		{"2", 2, 3, true},
+		{"2", 1, 3, false},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRequirement(tt.in)
+		if err != nil {
+			t.Fatalf("ParseRequirement(%q): %v", tt.in, err)
+		}
+		if got := r.Met(tt.passed, tt.total); got != tt.met {
+			t.Errorf("%q.Met(%d, %d) = %v, want %v", tt.in, tt.passed, tt.total, got, tt.met)
+		}
+	}
+
+	for _, bad := range []string{"0", "-1", "most"} {
+		if _, err := ParseRequirement(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
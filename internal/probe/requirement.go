@@ -0,0 +1,53 @@
+package probe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Requirement is how many probes of a VM must pass for it to be healthy
+type Requirement int
+
+const (
+	// RequireAll needs every probe to pass
+	RequireAll Requirement = 0
+	// RequireAny needs at least one probe to pass
+	RequireAny Requirement = 1
+)
+
+// ParseRequirement parses "all" (default), "any" or a number of probes
+func ParseRequirement(s string) (Requirement, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "all":
+		return RequireAll, nil
+	case "any":
+		return RequireAny, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("must be all, any or a positive number, got %q", s)
+	}
+	return Requirement(n), nil
+}
+
+// Met reports whether passed of total probes satisfy the requirement
+func (r Requirement) Met(passed, total int) bool {
+	if r == RequireAll {
+		return passed == total
+	}
+	return passed >= int(r)
+}
+
+// String returns the requirement as it is written in the configuration
+func (r Requirement) String() string {
+	switch r {
+	case RequireAll:
+		return "all"
+	case RequireAny:
+		return "any"
+	default:
+		return strconv.Itoa(int(r))
+	}
+}
@@ -0,0 +1,107 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Hours is a weekly power schedule: on between From and To on the selected days.
+// A range that ends before it starts runs past midnight into the next day.
+type Hours struct {
+	days     [7]bool
+	from, to int // minutes since midnight
+	location *time.Location
+}
+
+// NewHours parses a schedule. days is a list of weekdays and ranges such as
+// "mon-fri" or "mon,wed,fri" (empty means every day); from and to are HH:MM.
+func NewHours(days, from, to, timezone string) (*Hours, error) {
+	h := &Hours{}
+
+	var err error
+	if h.days, err = parseDays(days); err != nil {
+		return nil, err
+	}
+	if h.from, err = parseClock(from); err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	if h.to, err = parseClock(to); err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+	if h.from == h.to {
+		return nil, fmt.Errorf("from and to must differ")
+	}
+	if h.location, err = LoadLocation(timezone); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// On reports whether the schedule wants the VM running at t
+func (h *Hours) On(t time.Time) bool {
+	t = t.In(h.location)
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	if h.from < h.to {
+		return h.days[today] && minute >= h.from && minute < h.to
+	}
+	// Overnight: the evening part belongs to today, the morning part to yesterday's range
+	return (h.days[today] && minute >= h.from) || (h.days[yesterday] && minute < h.to)
+}
+
+func parseDays(spec string) ([7]bool, error) {
+	var days [7]bool
+	spec = strings.TrimSpace(strings.ToLower(spec))
+	if spec == "" || spec == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+
+		start, ok := weekdays[first]
+		if !ok {
+			return days, fmt.Errorf("unknown weekday %q", first)
+		}
+		end := start
+		if isRange {
+			if end, ok = weekdays[last]; !ok {
+				return days, fmt.Errorf("unknown weekday %q", last)
+			}
+		}
+
+		// Ranges may wrap around the week, e.g. fri-mon
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,144 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestCron_Matches(t *testing.T) {
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, moscow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		expr string
+		time string
+		want bool
+	}{
+		{"0 3 * * *", "2026-03-02 03:00", true},
+		{"0 3 * * *", "2026-03-02 03:01", false},
+		{"*/15 8-18 * * 1-5", "2026-03-02 08:45", true},  // Monday
+		{"*/15 8-18 * * 1-5", "2026-03-01 08:45", false}, // Sunday
+		{"0 2 * * 7", "2026-03-01 02:00", true},          // 7 is Sunday too
+		{"0 0 1 * 1", "2026-03-02 00:00", true},          // either day field matches
+		{"0 0 1,15 * *", "2026-03-15 00:00", true},
+		{"30 4 * 6 *", "2026-03-02 04:30", false},
+	}
+
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		if got := cron.Matches(at(tt.time)); got != tt.want {
+			t.Errorf("%q at %s = %v, want %v", tt.expr, tt.time, got, tt.want)
+		}
+	}
+}
+
+func TestWindow_Active(t *testing.T) {
+	// Sundays 02:00-04:00 Moscow time
+	w, err := NewWindow("0 2 * * 0", 2*time.Hour, "Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	sunday := time.Date(2026, 3, 1, 0, 0, 0, 0, moscow)
+
+	if active, _ := w.Active(sunday.Add(time.Hour + 59*time.Minute)); active {
+		t.Error("expected window to be inactive before it starts")
+	}
+
+	active, until := w.Active(sunday.Add(3 * time.Hour))
+	if !active || !until.Equal(sunday.Add(4*time.Hour)) {
+		t.Errorf("expected window active until 04:00, got %v until %v", active, until)
+	}
+
+	// The same instant expressed in UTC is still inside the window
+	if active, _ := w.Active(sunday.Add(3 * time.Hour).UTC()); !active {
+		t.Error("expected window to respect its timezone")
+	}
+
+	if active, _ := w.Active(sunday.Add(4 * time.Hour)); active {
+		t.Error("expected window to end after its duration")
+	}
+}
+
+func TestHours_On(t *testing.T) {
+	// 2026-03-02 is a Monday
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, moscow)
+	}
+
+	workday, err := NewHours("mon-fri", "08:00", "20:00", "Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	overnight, err := NewHours("fri", "22:00", "02:00", "Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		hours *Hours
+		t     time.Time
+		on    bool
+	}{
+		{"monday morning", workday, at(2, 8, 0), true},
+		{"monday before start", workday, at(2, 7, 59), false},
+		{"monday at end", workday, at(2, 20, 0), false},
+		{"saturday", workday, at(7, 12, 0), false},
+		{"other timezone", workday, at(2, 12, 0).UTC(), true},
+		{"friday night", overnight, at(6, 23, 0), true},
+		{"saturday early", overnight, at(7, 1, 59), true},
+		{"saturday late", overnight, at(7, 22, 30), false},
+		{"friday early", overnight, at(6, 1, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hours.On(tt.t); got != tt.on {
+				t.Errorf("On(%v) = %v, want %v", tt.t, got, tt.on)
+			}
+		})
+	}
+}
+
+func TestNewHours_Errors(t *testing.T) {
+	tests := []struct{ days, from, to, tz string }{
+		{"mon-fry", "08:00", "20:00", ""},
+		{"mon", "8am", "20:00", ""},
+		{"mon", "08:00", "24:00", ""},
+		{"mon", "08:00", "08:00", ""},
+		{"mon", "08:00", "20:00", "Mars/Olympus"},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewHours(tt.days, tt.from, tt.to, tt.tz); err == nil {
+			t.Errorf("expected error for %+v", tt)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxWindowDuration bounds maintenance windows; Active scans back minute by minute
+const MaxWindowDuration = 7 * 24 * time.Hour
+
+// Window is a recurring period that starts whenever its cron fires and lasts Duration
+type Window struct {
+	cron     *Cron
+	duration time.Duration
+	location *time.Location
+}
+
+// NewWindow parses a window; an empty timezone means the local one
+func NewWindow(cronExpr string, duration time.Duration, timezone string) (*Window, error) {
+	cron, err := ParseCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	if duration < time.Minute || duration > MaxWindowDuration {
+		return nil, fmt.Errorf("duration must be between 1m and %s", MaxWindowDuration)
+	}
+
+	location, err := LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Window{cron: cron, duration: duration, location: location}, nil
+}
+
+// LoadLocation resolves a timezone name; an empty name means the local timezone
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return location, nil
+}
+
+// Active reports whether t falls into the window and when that occurrence ends
+func (w *Window) Active(t time.Time) (bool, time.Time) {
+	t = t.In(w.location)
+	start := t.Truncate(time.Minute)
+
+	for elapsed := time.Duration(0); elapsed < w.duration; elapsed += time.Minute {
+		candidate := start.Add(-elapsed)
+		if w.cron.Matches(candidate) {
+			return true, candidate.Add(w.duration)
+		}
+	}
+	return false, time.Time{}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/monitoring"
+)
+
+// defaultPauseDuration is how long a pause lasts when the request gives no duration
+const defaultPauseDuration = time.Hour
+
+// Controller is the part of the coordinator exposed over the HTTP API
+type Controller interface {
+	VMStates() []monitoring.VMState
+	PauseVM(name string, d time.Duration, by, reason string) error
+	ResumeVM(name string) error
+}
+
+// VMStatus is the API representation of a monitored VM
+type VMStatus struct {
+	Name          string     `json:"name"`
+	Status        string     `json:"status"`
+	DesiredState  string     `json:"desired_state"`
+	Since         time.Time  `json:"since"`
+	IP            string     `json:"ip,omitempty"`
+	InMaintenance bool       `json:"in_maintenance"`
+	PausedUntil   *time.Time `json:"paused_until,omitempty"`
+	PausedBy      string     `json:"paused_by,omitempty"`
+	PauseReason   string     `json:"pause_reason,omitempty"`
+	CrashLooping  bool       `json:"crash_looping"`
+	Flapping      bool       `json:"flapping"`
+	AppDown       bool       `json:"app_down"`
+	AppFailure    string     `json:"app_failure,omitempty"`
+	RestartedAt   *time.Time `json:"restarted_at,omitempty"`
+	Incident      *Incident  `json:"incident,omitempty"`
+	LastIncident  *Incident  `json:"last_incident,omitempty"`
+}
+
+// Incident is the API representation of an open or resolved incident
+type Incident struct {
+	ID              string          `json:"id"`
+	OpenedAt        time.Time       `json:"opened_at"`
+	AcknowledgedBy  string          `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt  *time.Time      `json:"acknowledged_at,omitempty"`
+	ResolvedAt      *time.Time      `json:"resolved_at,omitempty"`
+	DowntimeSeconds float64         `json:"downtime_seconds"`
+	Escalations     int             `json:"escalations"`
+	Timeline        []IncidentEvent `json:"timeline"`
+}
+
+// IncidentEvent is an entry in an incident's timeline
+type IncidentEvent struct {
+	At   time.Time `json:"at"`
+	Text string    `json:"text"`
+}
+
+// NewAPI returns the operational API. Every request must carry
+// "Authorization: Bearer <token>".
+//
+//	GET  /api/vms                                      list VMs
+//	POST /api/vms/{name}/pause?duration=2h&reason=...  pause a VM
+//	POST /api/vms/{name}/resume                        lift a pause
+func NewAPI(controller Controller, token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/vms", func(w http.ResponseWriter, r *http.Request) {
+		states := controller.VMStates()
+		vms := make([]VMStatus, 0, len(states))
+		for _, s := range states {
+			vms = append(vms, toVMStatus(s))
+		}
+		writeJSON(w, http.StatusOK, vms)
+	})
+
+	mux.HandleFunc("POST /api/vms/{name}/pause", func(w http.ResponseWriter, r *http.Request) {
+		d := defaultPauseDuration
+		if v := r.URL.Query().Get("duration"); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed <= 0 {
+				writeError(w, http.StatusBadRequest, "duration must be a positive Go duration, e.g. 30m or 2h")
+				return
+			}
+			d = parsed
+		}
+
+		name := r.PathValue("name")
+		if err := controller.PauseVM(name, d, "http-api", r.URL.Query().Get("reason")); err != nil {
+			writeControllerError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status": "paused",
+			"until":  time.Now().Add(d).Format(time.RFC3339),
+		})
+	})
+
+	mux.HandleFunc("POST /api/vms/{name}/resume", func(w http.ResponseWriter, r *http.Request) {
+		if err := controller.ResumeVM(r.PathValue("name")); err != nil {
+			writeControllerError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "resumed"})
+	})
+
+	return requireToken(token, mux)
+}
+
+// requireToken rejects requests without the bearer token
+func requireToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(strings.TrimSpace(r.Header.Get("Authorization")))
+		if subtle.ConstantTimeCompare(got, expected) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func toVMStatus(s monitoring.VMState) VMStatus {
+	vm := VMStatus{
+		Name:          s.Name,
+		Status:        string(s.Status),
+		DesiredState:  string(s.Desired),
+		Since:         s.Since,
+		IP:            s.IP,
+		InMaintenance: s.InMaintenance,
+		CrashLooping:  s.CrashLooping,
+		Flapping:      s.Flapping,
+		AppDown:       s.AppDown,
+		AppFailure:    s.AppFailure,
+	}
+	if !s.RestartedAt.IsZero() {
+		restarted := s.RestartedAt
+		vm.RestartedAt = &restarted
+	}
+	vm.Incident = toIncident(s.Incident)
+	vm.LastIncident = toIncident(s.LastIncident)
+	if time.Now().Before(s.PausedUntil) {
+		until := s.PausedUntil
+		vm.PausedUntil = &until
+		vm.PausedBy = s.PausedBy
+		vm.PauseReason = s.PauseReason
+	}
+	return vm
+}
+
+func toIncident(inc *monitoring.Incident) *Incident {
+	if inc == nil {
+		return nil
+	}
+
+	out := &Incident{
+		ID:              inc.ID,
+		OpenedAt:        inc.OpenedAt,
+		AcknowledgedBy:  inc.AcknowledgedBy,
+		DowntimeSeconds: inc.Downtime().Round(time.Second).Seconds(),
+		Escalations:     inc.Escalations,
+		Timeline:        make([]IncidentEvent, 0, len(inc.Timeline)),
+	}
+	if !inc.AcknowledgedAt.IsZero() {
+		acknowledged := inc.AcknowledgedAt
+		out.AcknowledgedAt = &acknowledged
+	}
+	if !inc.ResolvedAt.IsZero() {
+		resolved := inc.ResolvedAt
+		out.ResolvedAt = &resolved
+	}
+	for _, e := range inc.Timeline {
+		out.Timeline = append(out.Timeline, IncidentEvent{At: e.At, Text: e.Text})
+	}
+	return out
+}
+
+func writeControllerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, monitoring.ErrUnknownVM):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, monitoring.ErrNotPaused):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
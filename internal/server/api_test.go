@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/monitoring"
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+type fakeController struct {
+	paused map[string]time.Duration
+	reason string
+}
+
+func (c *fakeController) VMStates() []monitoring.VMState {
+	return []monitoring.VMState{
+		{Name: "web", Status: types.StatusRunning},
+		{Name: "db", Status: types.StatusStopped, InMaintenance: true,
+			PausedUntil: time.Now().Add(time.Hour), PausedBy: "oncall"},
+	}
+}
+
+func (c *fakeController) PauseVM(name string, d time.Duration, by, reason string) error {
+	if name != "web" && name != "db" {
+		return monitoring.ErrUnknownVM
+	}
+	c.paused[name] = d
+	c.reason = reason
+	return nil
+}
+
+func (c *fakeController) ResumeVM(name string) error {
+	if _, ok := c.paused[name]; !ok {
+		return monitoring.ErrNotPaused
+	}
+	delete(c.paused, name)
+	return nil
+}
+
+func TestAPI(t *testing.T) {
+	controller := &fakeController{paused: make(map[string]time.Duration)}
+	api := NewAPI(controller, "secret")
+
+	do := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("GET", "/api/vms", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+	if rec := do("GET", "/api/vms", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", rec.Code)
+	}
+
+	rec := do("GET", "/api/vms", "secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var vms []VMStatus
+	if err := json.NewDecoder(rec.Body).Decode(&vms); err != nil {
+		t.Fatalf("failed to decode VM list: %v", err)
+	}
+	if len(vms) != 2 || vms[1].PausedUntil == nil || vms[1].PausedBy != "oncall" || vms[0].PausedUntil != nil {
+		t.Errorf("unexpected VM list: %+v", vms)
+	}
+
+	if rec := do("POST", "/api/vms/web/pause?duration=2h&reason=upgrade", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 on pause, got %d: %s", rec.Code, rec.Body)
+	}
+	if controller.paused["web"] != 2*time.Hour || controller.reason != "upgrade" {
+		t.Errorf("pause not forwarded: %v %q", controller.paused, controller.reason)
+	}
+
+	if rec := do("POST", "/api/vms/db/pause", "secret"); rec.Code != http.StatusOK || controller.paused["db"] != defaultPauseDuration {
+		t.Errorf("expected default pause duration, got %d %v", rec.Code, controller.paused)
+	}
+
+	tests := []struct {
+		method string
+		target string
+		code   int
+	}{
+		{"POST", "/api/vms/web/pause?duration=soon", http.StatusBadRequest},
+		{"POST", "/api/vms/web/pause?duration=-1h", http.StatusBadRequest},
+		{"POST", "/api/vms/nope/pause", http.StatusNotFound},
+		{"POST", "/api/vms/web/resume", http.StatusOK},
+		{"POST", "/api/vms/web/resume", http.StatusConflict},
+		{"GET", "/api/vms/web/resume", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if rec := do(tt.method, tt.target, "secret"); rec.Code != tt.code {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.target, tt.code, rec.Code)
+		}
+	}
+}
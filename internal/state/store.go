@@ -0,0 +1,124 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+// VMRecord is the persisted view of a monitored VM
+type VMRecord struct {
+	Status           types.VMStatus  `json:"status"`
+	Since            time.Time       `json:"since"`
+	GracePeriodUntil time.Time       `json:"grace_period_until,omitempty"`
+	IncidentID       string          `json:"incident_id,omitempty"`
+	IncidentStart    time.Time       `json:"incident_start,omitempty"`
+	AcknowledgedBy   string          `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt   time.Time       `json:"acknowledged_at,omitempty"`
+	IP               string          `json:"ip,omitempty"`
+	AutoStarts       []time.Time     `json:"auto_starts,omitempty"`
+	CrashLooping     bool            `json:"crash_looping,omitempty"`
+	PausedUntil      time.Time       `json:"paused_until,omitempty"`
+	PausedBy         string          `json:"paused_by,omitempty"`
+	PauseReason      string          `json:"pause_reason,omitempty"`
+	InMaintenance    bool            `json:"in_maintenance,omitempty"`
+	MutedUntil       time.Time       `json:"muted_until,omitempty"`
+	IncidentTimeline []IncidentEvent `json:"incident_timeline,omitempty"`
+	// IncidentEscalations is how many escalation steps the open incident took
+	IncidentEscalations int `json:"incident_escalations,omitempty"`
+}
+
+// IncidentEvent is an entry in the timeline of the VM's open incident
+type IncidentEvent struct {
+	At   time.Time `json:"at"`
+	Text string    `json:"text"`
+}
+
+// Store keeps monitor state in a JSON file so it survives restarts.
+// Every change is written through; changes happen on status transitions,
+// not on every check, so the file stays cheap to maintain.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	records map[string]VMRecord
+}
+
+// Open loads the store from path; a missing file yields an empty store
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		records: make(map[string]VMRecord),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	return s, nil
+}
+
+// Get returns the saved record of a VM
+func (s *Store) Get(name string) (VMRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[name]
+	return rec, ok
+}
+
+// Put saves the record of a VM, skipping the write if nothing changed
+func (s *Store) Put(name string, rec VMRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.records[name]; ok && reflect.DeepEqual(old, rec) {
+		return nil
+	}
+	s.records[name] = rec
+	return s.save()
+}
+
+// Delete forgets a VM that is no longer monitored
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[name]; !ok {
+		return nil
+	}
+	delete(s.records, name)
+	return s.save()
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
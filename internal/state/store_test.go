@@ -0,0 +1,68 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fxfuren/yandex-watcher-bot/internal/types"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, ok := store.Get("vm-1"); ok {
+		t.Fatal("expected empty store")
+	}
+
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rec := VMRecord{
+		Status:           types.StatusStopped,
+		Since:            since,
+		GracePeriodUntil: since.Add(time.Minute),
+		IncidentStart:    since,
+		IP:               "10.0.0.1",
+	}
+	if err := store.Put("vm-1", rec); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put("vm-2", VMRecord{Status: types.StatusRunning, Since: since}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Delete("vm-2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	got, ok := reopened.Get("vm-1")
+	if !ok {
+		t.Fatal("expected vm-1 to be restored")
+	}
+	if !got.Since.Equal(rec.Since) || got.Status != rec.Status || got.IP != rec.IP ||
+		!got.GracePeriodUntil.Equal(rec.GracePeriodUntil) || !got.IncidentStart.Equal(rec.IncidentStart) {
+		t.Errorf("restored %+v, want %+v", got, rec)
+	}
+	if _, ok := reopened.Get("vm-2"); ok {
+		t.Error("expected deleted vm-2 to stay deleted")
+	}
+}
+
+func TestOpen_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Fatal("expected error for corrupt state file")
+	}
+}
@@ -1,6 +1,29 @@
 package types
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultGracePeriod is how long checks are skipped after a start request
+	DefaultGracePeriod = 60 * time.Second
+	// DefaultMaxAutoStarts is how many automatic starts a VM gets per DefaultRestartWindow
+	DefaultMaxAutoStarts = 3
+	// DefaultRestartWindow is the sliding window of the restart budget
+	DefaultRestartWindow = time.Hour
+	// DefaultPingFailureThreshold is how many checks in a row must fail to ping
+	// a running VM before its status is requested from the API
+	DefaultPingFailureThreshold = 1
+	// DefaultRestartVerifyAfter is how long a restarted VM's application has to recover
+	DefaultRestartVerifyAfter = 5 * time.Minute
+	// DefaultFlapSamples is how many checks flap detection looks at
+	DefaultFlapSamples = 21
+	// DefaultFlapHigh is the percentage of state changes that starts flapping
+	DefaultFlapHigh = 50.0
+	// DefaultFlapLow is the percentage of state changes below which flapping ends
+	DefaultFlapLow = 25.0
+)
 
 // VMStatus represents the current status of a VM
 type VMStatus string
@@ -19,6 +42,47 @@ const (
 	StatusDeleting     VMStatus = "Deleting"
 )
 
+// DesiredState is the state the watchdog reconciles a VM towards
+type DesiredState string
+
+const (
+	// DesiredRunning VMs are started when they go down
+	DesiredRunning DesiredState = "running"
+	// DesiredStopped VMs are stopped when they are found running
+	DesiredStopped DesiredState = "stopped"
+	// DesiredUnmanaged VMs are observed and alerted on, but never started or stopped
+	DesiredUnmanaged DesiredState = "unmanaged"
+)
+
+// ParseDesiredState parses a desired state name; an empty name means running
+func ParseDesiredState(name string) (DesiredState, bool) {
+	switch d := DesiredState(strings.ToLower(name)); d {
+	case "":
+		return DesiredRunning, true
+	case DesiredRunning, DesiredStopped, DesiredUnmanaged:
+		return d, true
+	default:
+		return "", false
+	}
+}
+
+// AllStatuses lists every known status
+var AllStatuses = []VMStatus{
+	StatusUnknown, StatusRunning, StatusStopped, StatusStarting, StatusStopping,
+	StatusCrashed, StatusError, StatusProvisioning, StatusRestarting,
+	StatusUpdating, StatusDeleting,
+}
+
+// ParseVMStatus returns the status with the given name, ignoring case
+func ParseVMStatus(name string) (VMStatus, bool) {
+	for _, s := range AllStatuses {
+		if strings.EqualFold(string(s), name) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
 // IsCritical returns true if the status requires immediate action
 func (s VMStatus) IsCritical() bool {
 	switch s {
@@ -39,14 +103,9 @@ func (s VMStatus) IsTransitional() bool {
 	}
 }
 
-// ShouldStartVM returns true if we should attempt to start the VM in this status
-func (s VMStatus) ShouldStartVM() bool {
-	switch s {
-	case StatusStopped, StatusCrashed, StatusError:
-		return true
-	default:
-		return false
-	}
+// ShouldStartVM returns true if a VM in this status should be started to reach the desired state
+func (s VMStatus) ShouldStartVM(desired DesiredState) bool {
+	return desired == DesiredRunning && s.IsCritical()
 }
 
 // GetCheckInterval returns the recommended check interval for this status